@@ -34,7 +34,32 @@ func New() (*Account, bool) {
 	return &account, true
 }
 
+// Creates an account from an existing pair of x509 encoded keys
+// The private key may be nil for watch-only accounts
+func NewFromKeys(encodedPublicKey []byte, encodedPrivateKey []byte) *Account {
+	account := Account{
+		Address:    GetAddressFromPublicKey(encodedPublicKey),
+		PublicKey:  encodedPublicKey,
+		PrivateKey: encodedPrivateKey,
+	}
+
+	return &account
+}
+
 // Given a public key, return the corresponding address
 func GetAddressFromPublicKey(publicKey []byte) common.Address {
 	return common.BytesToAddress(crypto.HashBytes(publicKey).Bytes())
 }
+
+// Returns true if the account's private key is not held in memory
+func (account *Account) IsLocked() bool {
+	return account.PrivateKey == nil
+}
+
+// Erases the account's private key from memory
+func (account *Account) Lock() {
+	for i := range account.PrivateKey {
+		account.PrivateKey[i] = 0
+	}
+	account.PrivateKey = nil
+}
@@ -11,6 +11,11 @@ import (
 	"github.com/AndrewCLu/TestcoinNode/util"
 )
 
+const (
+	BlockHeaderLengthLength      = 2 // The number of bytes used to designate the length of a serialized header
+	BlockTransactionLengthLength = 4 // The number of bytes used to designate a transaction count or the length of a serialized transaction
+)
+
 // A block is a collection of transactions, including a coinbase, with a header containing metadata
 type Block struct {
 	Header   *BlockHeader               `json:"header"`
@@ -37,20 +42,8 @@ func New(
 	transactions []*transaction.Transaction,
 	coinbase *transaction.Transaction,
 ) (blk *Block, ok bool) {
-	// Check number of transactions is under the limit
-	if len(transactions) > protocol.MaxTransactionsInBlock {
-		fmt.Println("Number of transactions exceeds max allowable.")
-		return nil, false
-	}
-
 	// Hash all the transactions, including coinbase
-	transactionHashes := make([][]byte, len(transactions)+1)
-	for i, tx := range transactions {
-		hash := tx.Hash()
-		transactionHashes[i] = hash.Bytes()
-	}
-	transactionHashes[len(transactions)] = coinbase.Hash().Bytes()
-	allTransactionsHash := crypto.HashBytes(util.ConcatByteSlices(transactionHashes))
+	allTransactionsHash := ComputeAllTransactionsHash(protocol.CurrentProtocolVersion, transactions, coinbase)
 
 	header := BlockHeader{
 		ProtocolVersion:     protocol.CurrentProtocolVersion,
@@ -67,9 +60,104 @@ func New(
 		Coinbase: coinbase,
 	}
 
+	// Check size of the block is under the limit
+	if block.Size() > protocol.MaxBlockSize {
+		fmt.Println("Size of block exceeds max allowable.")
+		return nil, false
+	}
+
 	return &block, true
 }
 
+// Returns the size of a block in bytes, which is the size of its header, transactions and coinbase
+func (b *Block) Size() int {
+	size := len(b.Header.Bytes()) + b.Coinbase.Size()
+	for _, tx := range b.Body {
+		size += tx.Size()
+	}
+
+	return size
+}
+
+// Converts a Block into byte representation
+// The header, coinbase and each body transaction are length prefixed, and the body is preceded by its transaction count
+func (b *Block) Bytes() []byte {
+	headerBytes := b.Header.Bytes()
+
+	coinbaseBytes := b.Coinbase.Bytes()
+
+	allBytes := [][]byte{
+		util.Uint16ToBytes(uint16(len(headerBytes))),
+		headerBytes,
+		util.Uint32ToBytes(uint32(len(coinbaseBytes))),
+		coinbaseBytes,
+		util.Uint32ToBytes(uint32(len(b.Body))),
+	}
+	for _, tx := range b.Body {
+		txBytes := tx.Bytes()
+		allBytes = append(allBytes, util.Uint32ToBytes(uint32(len(txBytes))), txBytes)
+	}
+
+	return util.ConcatByteSlices(allBytes)
+}
+
+// Converts bytes back to a Block
+// Returns bool indicating success, which fails if the bytes are not a well formed block
+func BytesToBlock(bytes []byte) (blk *Block, ok bool) {
+	currentByte := 0
+	next := func(length int) []byte {
+		if length < 0 || len(bytes)-currentByte < length {
+			ok = false
+			return nil
+		}
+		field := bytes[currentByte : currentByte+length]
+		currentByte += length
+
+		return field
+	}
+	ok = true
+
+	headerLength := next(BlockHeaderLengthLength)
+	if !ok {
+		return nil, false
+	}
+	header, headerOk := BytesToBlockHeader(next(int(util.BytesToUint16(headerLength))))
+	if !ok || !headerOk {
+		return nil, false
+	}
+
+	coinbaseLength := next(BlockTransactionLengthLength)
+	if !ok {
+		return nil, false
+	}
+	coinbase := transaction.BytesToTransaction(next(int(util.BytesToUint32(coinbaseLength))))
+	if !ok || coinbase == nil {
+		return nil, false
+	}
+
+	numTransactions := next(BlockTransactionLengthLength)
+	if !ok {
+		return nil, false
+	}
+	body := []*transaction.Transaction{}
+	for i := uint32(0); i < util.BytesToUint32(numTransactions); i++ {
+		txLength := next(BlockTransactionLengthLength)
+		if !ok {
+			return nil, false
+		}
+		tx := transaction.BytesToTransaction(next(int(util.BytesToUint32(txLength))))
+		if !ok || tx == nil {
+			return nil, false
+		}
+		body = append(body, tx)
+	}
+	if currentByte != len(bytes) {
+		return nil, false
+	}
+
+	return &Block{Header: header, Body: body, Coinbase: coinbase}, true
+}
+
 // Converts a BlockHeader into byte representation
 func (header *BlockHeader) Bytes() []byte {
 	versionBytes := util.Uint16ToBytes(header.ProtocolVersion)
@@ -99,6 +187,33 @@ func (header *BlockHeader) Bytes() []byte {
 	return util.ConcatByteSlices(allBytes)
 }
 
+// Converts bytes back to a BlockHeader
+// Returns bool indicating success, which fails if the bytes are too short or the timestamp is malformed
+func BytesToBlockHeader(bytes []byte) (header *BlockHeader, ok bool) {
+	const fixedLength = protocol.ProtocolVersionLength + 2*common.HashLength + common.TargetLength + 4
+	if len(bytes) <= fixedLength {
+		return nil, false
+	}
+
+	timeStart := protocol.ProtocolVersionLength + 2*common.HashLength
+	timeEnd := len(bytes) - common.TargetLength - 4
+	var timestamp time.Time
+	if err := timestamp.UnmarshalBinary(bytes[timeStart:timeEnd]); err != nil {
+		return nil, false
+	}
+
+	header = &BlockHeader{
+		ProtocolVersion:     util.BytesToUint16(bytes[:protocol.ProtocolVersionLength]),
+		PreviousBlockHash:   common.BytesToHash(bytes[protocol.ProtocolVersionLength : protocol.ProtocolVersionLength+common.HashLength]),
+		AllTransactionsHash: common.BytesToHash(bytes[protocol.ProtocolVersionLength+common.HashLength : timeStart]),
+		Timestamp:           timestamp,
+		Target:              common.BytesToTarget(bytes[timeEnd : timeEnd+common.TargetLength]),
+		Nonce:               util.BytesToUint32(bytes[timeEnd+common.TargetLength:]),
+	}
+
+	return header, true
+}
+
 // Returns the hash of a block, which is simply the hash of the block header
 func (b *Block) Hash() common.Hash {
 	return b.Header.Hash()
@@ -0,0 +1,33 @@
+package block
+
+import (
+	"testing"
+)
+
+// Tests that converting a block into bytes and back yields the same block
+func TestBlockToByteArray(t *testing.T) {
+	for numTransactions := 0; numTransactions < 3; numTransactions++ {
+		blk := newTestBlock(numTransactions)
+		blkBytes := blk.Bytes()
+
+		decoded, ok := BytesToBlock(blkBytes)
+		if !ok {
+			t.Fatalf(`Failed to decode block with %v transactions`, numTransactions)
+		}
+		if !decoded.Hash().Equal(blk.Hash()) || !decoded.Coinbase.Equal(blk.Coinbase) || len(decoded.Body) != numTransactions {
+			t.Fatalf(`Decoded block is not equal to original`)
+		}
+		for i, tx := range decoded.Body {
+			if !tx.Equal(blk.Body[i]) {
+				t.Fatalf(`Decoded transaction %v is not equal to original`, i)
+			}
+		}
+
+		if _, ok := BytesToBlock(blkBytes[:len(blkBytes)-1]); ok {
+			t.Fatalf(`Decoded a truncated block`)
+		}
+		if _, ok := BytesToBlock(append(blkBytes, 0)); ok {
+			t.Fatalf(`Decoded a block with trailing bytes`)
+		}
+	}
+}
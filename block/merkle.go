@@ -0,0 +1,173 @@
+package block
+
+import (
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+const (
+	merkleLeafPrefix     = byte(0) // Prepended to transaction hashes before hashing them into a leaf
+	merkleInternalPrefix = byte(1) // Prepended to a pair of child hashes before hashing them into a parent
+)
+
+// A Merkle proof step is the sibling hash needed to compute the next level of the tree
+type MerkleProofStep struct {
+	Hash   common.Hash `json:"hash"`
+	IsLeft bool        `json:"isLeft"` // True if the sibling is the left child of the parent
+}
+
+// A Merkle proof is the path of sibling hashes from a transaction leaf up to the root of a block's Merkle tree
+type MerkleProof struct {
+	Steps []*MerkleProofStep `json:"steps"`
+}
+
+// Computes the hash committing to all transactions of a block, including the coinbase
+// Blocks before the Merkle tree protocol version hash the concatenation of all transaction hashes
+func ComputeAllTransactionsHash(
+	protocolVersion uint16,
+	transactions []*transaction.Transaction,
+	coinbase *transaction.Transaction,
+) common.Hash {
+	transactionHashes := getTransactionHashes(transactions, coinbase)
+
+	if protocolVersion < protocol.MerkleTreeProtocolVersion {
+		hashBytes := make([][]byte, len(transactionHashes))
+		for i, hash := range transactionHashes {
+			hashBytes[i] = hash.Bytes()
+		}
+
+		return crypto.HashBytes(util.ConcatByteSlices(hashBytes))
+	}
+
+	return ComputeMerkleRoot(transactionHashes)
+}
+
+// Given a list of transaction hashes, computes the root of the Merkle tree with the hashes as leaves
+// A node without a sibling is promoted to the next level unchanged
+func ComputeMerkleRoot(transactionHashes []common.Hash) common.Hash {
+	if len(transactionHashes) == 0 {
+		return common.Hash{}
+	}
+
+	level := make([]common.Hash, len(transactionHashes))
+	for i, hash := range transactionHashes {
+		level[i] = hashMerkleLeaf(hash)
+	}
+
+	for len(level) > 1 {
+		level = nextMerkleLevel(level)
+	}
+
+	return level[0]
+}
+
+// Returns the list of transaction hashes of a block in the order they are committed to, with the coinbase last
+func (b *Block) TransactionHashes() []common.Hash {
+	return getTransactionHashes(b.Body, b.Coinbase)
+}
+
+// Generates a proof that the transaction with the given hash is included in the block
+// Returns a bool indicating success, which fails if the transaction is not in the block
+// or the block does not commit to its transactions with a Merkle tree
+func (b *Block) NewMerkleProof(transactionHash common.Hash) (proof *MerkleProof, ok bool) {
+	if b.Header.ProtocolVersion < protocol.MerkleTreeProtocolVersion {
+		return nil, false
+	}
+
+	transactionHashes := b.TransactionHashes()
+	index := -1
+	for i, hash := range transactionHashes {
+		if hash.Equal(transactionHash) {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return nil, false
+	}
+
+	level := make([]common.Hash, len(transactionHashes))
+	for i, hash := range transactionHashes {
+		level[i] = hashMerkleLeaf(hash)
+	}
+
+	steps := []*MerkleProofStep{}
+	for len(level) > 1 {
+		// Nodes without a sibling are promoted, so they contribute no step at this level
+		if index%2 == 1 {
+			steps = append(steps, &MerkleProofStep{Hash: level[index-1], IsLeft: true})
+		} else if index+1 < len(level) {
+			steps = append(steps, &MerkleProofStep{Hash: level[index+1], IsLeft: false})
+		}
+
+		level = nextMerkleLevel(level)
+		index /= 2
+	}
+
+	return &MerkleProof{Steps: steps}, true
+}
+
+// Returns true if the proof shows that the transaction hash is a leaf of the Merkle tree with the given root
+func VerifyMerkleProof(transactionHash common.Hash, root common.Hash, proof *MerkleProof) bool {
+	if proof == nil {
+		return false
+	}
+
+	current := hashMerkleLeaf(transactionHash)
+	for _, step := range proof.Steps {
+		if step.IsLeft {
+			current = hashMerkleNode(step.Hash, current)
+		} else {
+			current = hashMerkleNode(current, step.Hash)
+		}
+	}
+
+	return current.Equal(root)
+}
+
+// Returns true if the proof shows that the transaction hash is committed to by the given block header
+func VerifyTransactionInclusion(header *BlockHeader, transactionHash common.Hash, proof *MerkleProof) bool {
+	if header.ProtocolVersion < protocol.MerkleTreeProtocolVersion {
+		return false
+	}
+
+	return VerifyMerkleProof(transactionHash, header.AllTransactionsHash, proof)
+}
+
+// Returns the hashes of the given transactions followed by the hash of the coinbase
+func getTransactionHashes(transactions []*transaction.Transaction, coinbase *transaction.Transaction) []common.Hash {
+	transactionHashes := make([]common.Hash, len(transactions)+1)
+	for i, tx := range transactions {
+		transactionHashes[i] = tx.Hash()
+	}
+	transactionHashes[len(transactions)] = coinbase.Hash()
+
+	return transactionHashes
+}
+
+// Hashes pairs of nodes in one level of the tree to compute the level above it
+func nextMerkleLevel(level []common.Hash) []common.Hash {
+	next := make([]common.Hash, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+		} else {
+			next = append(next, hashMerkleNode(level[i], level[i+1]))
+		}
+	}
+
+	return next
+}
+
+// Hashes a transaction hash into a Merkle leaf
+func hashMerkleLeaf(transactionHash common.Hash) common.Hash {
+	return crypto.HashBytes(util.ConcatByteSlices([][]byte{{merkleLeafPrefix}, transactionHash.Bytes()}))
+}
+
+// Hashes two child nodes into their parent node
+func hashMerkleNode(left common.Hash, right common.Hash) common.Hash {
+	return crypto.HashBytes(util.ConcatByteSlices([][]byte{{merkleInternalPrefix}, left.Bytes(), right.Bytes()}))
+}
@@ -0,0 +1,94 @@
+package block
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// Creates a block with the given number of transactions in its body
+func newTestBlock(numTransactions int) *Block {
+	transactions := []*transaction.Transaction{}
+	for i := 0; i < numTransactions; i++ {
+		output := &transaction.TransactionOutput{ReceiverAddress: common.Address{byte(i)}, Amount: uint64(i + 1)}
+		tx, _ := transaction.New([]*transaction.TransactionInput{}, []*transaction.TransactionOutput{output})
+		transactions = append(transactions, tx)
+	}
+
+	coinbaseOutput := &transaction.TransactionOutput{ReceiverAddress: common.Address{255}, Amount: 10}
+	coinbase, _ := transaction.New([]*transaction.TransactionInput{}, []*transaction.TransactionOutput{coinbaseOutput})
+
+	blk := &Block{
+		Header: &BlockHeader{
+			ProtocolVersion: protocol.CurrentProtocolVersion,
+		},
+		Body:     transactions,
+		Coinbase: coinbase,
+	}
+	blk.Header.AllTransactionsHash = ComputeAllTransactionsHash(blk.Header.ProtocolVersion, blk.Body, blk.Coinbase)
+
+	return blk
+}
+
+// Tests that a proof for every transaction in blocks of various sizes verifies against the block header
+func TestMerkleProofs(t *testing.T) {
+	for numTransactions := 0; numTransactions < 8; numTransactions++ {
+		blk := newTestBlock(numTransactions)
+
+		for _, hash := range blk.TransactionHashes() {
+			proof, ok := blk.NewMerkleProof(hash)
+			if !ok {
+				t.Fatalf(`Failed to create proof for transaction %v in block with %v transactions`, hash.Hex(), numTransactions)
+			}
+
+			if !VerifyTransactionInclusion(blk.Header, hash, proof) {
+				t.Fatalf(`Proof for transaction %v in block with %v transactions did not verify`, hash.Hex(), numTransactions)
+			}
+		}
+	}
+}
+
+// Tests that proofs fail for transactions not in the block or with tampered steps
+func TestInvalidMerkleProofs(t *testing.T) {
+	blk := newTestBlock(5)
+	hashes := blk.TransactionHashes()
+
+	missingHash := crypto.HashBytes([]byte("missing"))
+	if _, ok := blk.NewMerkleProof(missingHash); ok {
+		t.Fatalf(`Created a proof for a transaction that is not in the block`)
+	}
+
+	proof, _ := blk.NewMerkleProof(hashes[2])
+	if VerifyTransactionInclusion(blk.Header, hashes[3], proof) {
+		t.Fatalf(`Proof verified for a different transaction than it was created for`)
+	}
+
+	proof.Steps[0].IsLeft = !proof.Steps[0].IsLeft
+	if VerifyTransactionInclusion(blk.Header, hashes[2], proof) {
+		t.Fatalf(`Proof verified after its steps were tampered with`)
+	}
+}
+
+// Tests that blocks using the legacy transactions hash do not produce or accept Merkle proofs
+func TestLegacyBlockMerkleProofs(t *testing.T) {
+	blk := newTestBlock(3)
+	hash := blk.TransactionHashes()[0]
+	proof, _ := blk.NewMerkleProof(hash)
+
+	blk.Header.ProtocolVersion = protocol.MerkleTreeProtocolVersion - 1
+	if _, ok := blk.NewMerkleProof(hash); ok {
+		t.Fatalf(`Created a Merkle proof for a legacy block`)
+	}
+
+	if VerifyTransactionInclusion(blk.Header, hash, proof) {
+		t.Fatalf(`Verified a Merkle proof against a legacy block header`)
+	}
+
+	legacyHash := ComputeAllTransactionsHash(blk.Header.ProtocolVersion, blk.Body, blk.Coinbase)
+	if legacyHash.Equal(ComputeMerkleRoot(blk.TransactionHashes())) {
+		t.Fatalf(`Legacy transactions hash should differ from the Merkle root`)
+	}
+}
@@ -2,89 +2,166 @@ package chain
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/AndrewCLu/TestcoinNode/block"
 	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/logging"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
 	"github.com/AndrewCLu/TestcoinNode/transaction"
 	"github.com/AndrewCLu/TestcoinNode/util"
 )
 
+var log = logging.Get("chain")
+
+// A chain is the full ledger kept by a full node: its blocks, confirmed transactions, unspent outputs and pending pool
+// All state is guarded by a read write lock, so any number of queries can run concurrently while blocks are added
 type Chain struct {
-	Blocks              map[common.Hash]*block.Block
-	Transactions        map[common.Hash]*transaction.Transaction
-	LastBlockHash       common.Hash
-	PendingTransactions []*transaction.Transaction
-	UnspentOutputs      map[common.Address][]*transaction.TransactionOutputPointer
+	mu                  sync.RWMutex
+	params              *protocol.Params             // The parameters of the network this chain belongs to
+	blocks              map[common.Hash]*block.Block // Blocks whose bodies have not been pruned
+	headers             map[common.Hash]*block.BlockHeader
+	blockHashes         []common.Hash // Block hashes indexed by block number
+	transactions        map[common.Hash]*transaction.Transaction
+	lastBlockHash       common.Hash
+	pendingTransactions []*transaction.Transaction
+	utxos               *UtxoSet
+	undo                map[common.Hash][][]spentOutput // The outputs spent by each body transaction of a block
+	txIndex             *transactionIndex               // Nil unless the transaction index is enabled
+	addrIndex           *addressIndex                   // Nil unless the address index is enabled
+	pruning             *PruneConfig                    // Nil unless pruning is enabled
+	pruneHeight         int                             // The number of the oldest block that still has its body
+	blockBytes          int64                           // The size of all blocks that still have their bodies
 }
 
-// Sets up the initial state of the chain
-func New() (chn *Chain, ok bool) {
+// Sets up the initial state of the chain for the network with the given parameters
+func New(params *protocol.Params) (chn *Chain, ok bool) {
 	chain := Chain{
-		Blocks:              make(map[common.Hash]*block.Block),
-		Transactions:        make(map[common.Hash]*transaction.Transaction),
-		LastBlockHash:       *new(common.Hash),
-		PendingTransactions: []*transaction.Transaction{},
-		UnspentOutputs:      make(map[common.Address][]*transaction.TransactionOutputPointer),
+		params:              params,
+		blocks:              make(map[common.Hash]*block.Block),
+		headers:             make(map[common.Hash]*block.BlockHeader),
+		blockHashes:         []common.Hash{},
+		transactions:        make(map[common.Hash]*transaction.Transaction),
+		lastBlockHash:       *new(common.Hash),
+		pendingTransactions: []*transaction.Transaction{},
+		utxos:               NewUtxoSet(),
+		undo:                make(map[common.Hash][][]spentOutput),
 	}
 
 	return &chain, true
 }
 
+// Returns the parameters of the network this chain belongs to
+func (chain *Chain) Params() *protocol.Params {
+	return chain.params
+}
+
 // Initializes the chain with a genesis block, return a bool indicating success
+// Fails if the chain already has blocks
 func (chain *Chain) Initialize(genesisBlock *block.Block) bool {
-	genesisBlockHash := genesisBlock.Hash()
-	chain.Blocks[genesisBlockHash] = genesisBlock
-	chain.LastBlockHash = genesisBlockHash
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
 
-	chain.AddBlock(genesisBlock)
+	if len(chain.blockHashes) != 0 {
+		return false
+	}
+	chain.addBlock(genesisBlock)
 
 	return true
 }
 
 // Given a transaction hash, returns a pointer to the transaction
-// Returns bool indicating success
+// Returns bool indicating success, which fails if the transaction is not confirmed or its block has been pruned
 func (chain *Chain) GetTransaction(hash common.Hash) (tx *transaction.Transaction, ok bool) {
-	return chain.Transactions[hash], true
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	tx, ok = chain.transactions[hash]
+	return tx, ok
 }
 
 // Given a pending transaction, return its transaction fee
+// Returns bool indicating success, which fails if an input is unknown, a total overflows, or outputs exceed inputs
 func (chain *Chain) GetPendingTransactionFee(tx *transaction.Transaction) (fee uint64, ok bool) {
-	var inputs uint64 = 0
-	var outputs uint64 = 0
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return computeFee(tx, chain.getOutputAmount)
+}
+
+// Computes the fee of a transaction, looking up the amount of each input with outputAmount
+func computeFee(tx *transaction.Transaction, outputAmount func(*transaction.TransactionOutputPointer) (uint64, bool)) (fee uint64, ok bool) {
+	var inputs util.Amount = 0
+	var outputs util.Amount = 0
 	for _, input := range tx.Inputs {
-		inputAmount, inputOk := chain.GetOutputAmount(input.OutputPointer)
+		inputAmount, inputOk := outputAmount(input.OutputPointer)
 		if !inputOk {
 			return 0, false
 		}
-		inputs += inputAmount
+		inputs, ok = inputs.Add(util.Amount(inputAmount))
+		if !ok {
+			return 0, false
+		}
 	}
 	for _, output := range tx.Outputs {
-		outputs += output.Amount
+		outputs, ok = outputs.Add(util.Amount(output.Amount))
+		if !ok {
+			return 0, false
+		}
+	}
+
+	difference, ok := inputs.Sub(outputs)
+	return uint64(difference), ok
+}
+
+// Given a pending transaction, return its fee rate in base units per byte
+func (chain *Chain) GetPendingTransactionFeeRate(tx *transaction.Transaction) (feeRate uint64, ok bool) {
+	fee, ok := chain.GetPendingTransactionFee(tx)
+	if !ok {
+		return 0, false
 	}
 
-	return inputs - outputs, true
+	return fee / uint64(tx.Size()), true
 }
 
 // Gets up to num pending transactions
+// The returned slice is a copy, so it is safe to use while the pool changes
 // Returns bool indicating success
 // TODO: Have a ranking of pending transactions to retrieve by time added or miner fee
 func (chain *Chain) GetPendingTransactions(num int) (txs []*transaction.Transaction, ok bool) {
-	txs = chain.PendingTransactions
-	if num > len(txs) {
-		return txs, true
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	if num > len(chain.pendingTransactions) || num < 0 {
+		num = len(chain.pendingTransactions)
 	}
+	txs = make([]*transaction.Transaction, num)
+	copy(txs, chain.pendingTransactions)
 
 	return txs, true
 }
 
-// Gets up to num pending transactions
+// Returns the number of transactions in the pending pool
+func (chain *Chain) NumPendingTransactions() int {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return len(chain.pendingTransactions)
+}
+
+// Gets the pending transactions that spend outputs belonging to address
 // Returns bool indicating success
 func (chain *Chain) GetPendingTransactionsByAddress(address common.Address) (txs []*transaction.Transaction, ok bool) {
-	for _, tx := range chain.PendingTransactions {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	for _, tx := range chain.pendingTransactions {
 		for _, input := range tx.Inputs {
-			outputTx := chain.Transactions[input.OutputPointer.TransactionHash]
-			inputAddress := outputTx.Outputs[input.OutputPointer.OutputIndex].ReceiverAddress
-			if inputAddress.Equal(address) {
+			entry, ok := chain.utxos.Get(*input.OutputPointer)
+			if !ok {
+				continue
+			}
+			if entry.Owner.Equal(address) {
 				txs = append(txs, tx)
 				break
 			}
@@ -97,7 +174,10 @@ func (chain *Chain) GetPendingTransactionsByAddress(address common.Address) (txs
 // Add a pending transaction to the list
 // Returns bool indicating success
 func (chain *Chain) AddPendingTransaction(tx *transaction.Transaction) bool {
-	chain.PendingTransactions = append(chain.PendingTransactions, tx)
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.pendingTransactions = append(chain.pendingTransactions, tx)
 
 	return true
 }
@@ -105,187 +185,505 @@ func (chain *Chain) AddPendingTransaction(tx *transaction.Transaction) bool {
 // Removes a list of pending transactions from the pool
 // Returns a bool indicating success
 func (chain *Chain) RemovePendingTransactions(txs []*transaction.Transaction) bool {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
 	for _, tx := range txs {
-		ind := -1
-		for i, ptx := range chain.PendingTransactions {
-			if tx.Equal(ptx) {
-				ind = i
-			}
-		}
+		chain.removePendingTransaction(tx)
+	}
+
+	return true
+}
 
-		if ind != -1 {
-			// Remove pending transaction at index ind
-			chain.PendingTransactions[ind] = chain.PendingTransactions[len(chain.PendingTransactions)-1]
-			chain.PendingTransactions = chain.PendingTransactions[:len(chain.PendingTransactions)-1]
+// Removes a transaction from the pending pool if it is there
+func (chain *Chain) removePendingTransaction(tx *transaction.Transaction) {
+	ind := -1
+	for i, ptx := range chain.pendingTransactions {
+		if tx.Equal(ptx) {
+			ind = i
 		}
 	}
 
-	return true
+	if ind != -1 {
+		// Remove pending transaction at index ind
+		chain.pendingTransactions[ind] = chain.pendingTransactions[len(chain.pendingTransactions)-1]
+		chain.pendingTransactions = chain.pendingTransactions[:len(chain.pendingTransactions)-1]
+	}
 }
 
 // Get information about the last block in the chain
 // Gets the hash and block number of the last blcok
 // Returns bool indicating success
 func (chain *Chain) GetLastBlockInfo() (hash common.Hash, blockNum int, ok bool) {
-	return chain.LastBlockHash, len(chain.Blocks) - 1, true
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.getLastBlockInfo()
+}
+
+func (chain *Chain) getLastBlockInfo() (hash common.Hash, blockNum int, ok bool) {
+	return chain.lastBlockHash, len(chain.blockHashes) - 1, true
 }
 
-// Adds a confirmed transaction to the chain
+// Adds a confirmed transaction to the chain as if it were in the next block
 // Returns bool indicating success
 // This is not a smart function - it will add the transaction, update the pending transactions and the utxos without validation
 func (chain *Chain) AddTransaction(tx *transaction.Transaction) (ok bool) {
-	// Find matching pending transaction
-	ind := -1
-	for i, ptx := range chain.PendingTransactions {
-		if tx.Equal(ptx) {
-			ind = i
-		}
-	}
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
 
-	if ind != -1 {
-		// Remove pending transaction at index ind
-		chain.PendingTransactions[ind] = chain.PendingTransactions[len(chain.PendingTransactions)-1]
-		chain.PendingTransactions = chain.PendingTransactions[:len(chain.PendingTransactions)-1]
-	}
+	chain.addTransaction(tx, len(chain.blockHashes))
+
+	return true
+}
+
+// Adds a transaction confirmed in the given block, spending its inputs and creating its outputs
+// Transactions without inputs are coinbases
+// Returns the outputs the transaction spent
+func (chain *Chain) addTransaction(tx *transaction.Transaction, blockNum int) (spent []spentOutput) {
+	chain.removePendingTransaction(tx)
 
 	// Add new transaction
 	txHash := tx.Hash()
-	chain.Transactions[txHash] = tx
+	chain.transactions[txHash] = tx
 
 	for _, input := range tx.Inputs {
-		ptr := input.OutputPointer
-		outputTx := chain.Transactions[ptr.TransactionHash]
-		address := outputTx.Outputs[ptr.OutputIndex].ReceiverAddress
-
-		// Find matching utxo
-		utxoInd := -1
-		for j, utxoPtr := range chain.UnspentOutputs[address] {
-			if ptr.Equal(utxoPtr) {
-				utxoInd = j
-			}
-		}
-
-		if utxoInd != -1 {
-			// Remove utxo pointer at index utxoInd
-			chain.UnspentOutputs[address][utxoInd] = chain.UnspentOutputs[address][len(chain.UnspentOutputs[address])-1]
-			chain.UnspentOutputs[address] = chain.UnspentOutputs[address][:len(chain.UnspentOutputs[address])-1]
+		if entry, ok := chain.utxos.Remove(*input.OutputPointer); ok {
+			spent = append(spent, spentOutput{OutputPointer: *input.OutputPointer, Entry: entry})
 		}
 	}
 
+	isCoinbase := len(tx.Inputs) == 0
 	for outputIndex, output := range tx.Outputs {
-		// Put new utxo pointer in
 		outputPointer := transaction.TransactionOutputPointer{
 			TransactionHash: txHash,
 			OutputIndex:     uint16(outputIndex),
 		}
-		receiverAddress := output.ReceiverAddress
-		chain.UnspentOutputs[receiverAddress] = append(chain.UnspentOutputs[receiverAddress], &outputPointer)
+		chain.utxos.Add(outputPointer, UtxoEntry{
+			Amount:     output.Amount,
+			Owner:      output.ReceiverAddress,
+			BlockNum:   blockNum,
+			IsCoinbase: isCoinbase,
+		})
 	}
 
-	return true
+	return spent
 }
 
 // Add a block to the chain
-// Returns bool indicating success
+// Returns bool indicating success, which fails if the block does not build on the current tip
 // This is not a smart function - it will add the block without validation
 func (chain *Chain) AddBlock(block *block.Block) (ok bool) {
-	for _, tx := range block.Body {
-		fmt.Printf("Adding transaction to chain %v\n", tx.Hash().Hex())
-		chain.AddTransaction(tx)
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	// Another block may have been added since this one was validated
+	if len(chain.blockHashes) > 0 && !block.Header.PreviousBlockHash.Equal(chain.lastBlockHash) {
+		return false
 	}
-	fmt.Printf("Adding coinbase to chain: %v\n", block.Coinbase.Hash().Hex())
-	chain.AddTransaction(block.Coinbase)
+	chain.addBlock(block)
+
+	return true
+}
+
+func (chain *Chain) addBlock(block *block.Block) {
+	blockNum := len(chain.blockHashes)
+	undo := make([][]spentOutput, len(block.Body))
+	for i, tx := range block.Body {
+		log.Debug("Adding transaction to chain", "tx", tx.Hash().Hex(), "height", blockNum)
+		undo[i] = chain.addTransaction(tx, blockNum)
+	}
+	log.Debug("Adding coinbase to chain", "tx", block.Coinbase.Hash().Hex(), "height", blockNum)
+	chain.addTransaction(block.Coinbase, blockNum)
 
 	// Add new block
 	hash := block.Hash()
-	chain.Blocks[hash] = block
+	chain.blocks[hash] = block
+	chain.headers[hash] = block.Header
+	chain.blockHashes = append(chain.blockHashes, hash)
+	chain.blockBytes += int64(block.Size())
+	chain.undo[hash] = undo
+	chain.connectIndexes(block, hash, blockNum)
 
 	// Update last block hash
-	chain.LastBlockHash = hash
+	chain.lastBlockHash = hash
+	log.Info("Added block to chain", "hash", hash.Hex(), "height", blockNum, "txs", len(block.Body))
+
+	chain.prune()
+}
+
+// Removes the last block from the chain, restoring the outputs it spent and returning its body to the pending pool
+// Returns the removed block and a bool indicating success, which fails if only the genesis block is left or the tip has been pruned
+// A pruning chain also refuses to disconnect blocks once fewer than MinPruneDepth full blocks would be left
+func (chain *Chain) DisconnectTip() (blk *block.Block, ok bool) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	blockNum := len(chain.blockHashes) - 1
+	if blockNum < 1 || blockNum < chain.pruneHeight {
+		return nil, false
+	}
+	if chain.pruning != nil && blockNum-chain.pruneHeight < MinPruneDepth {
+		log.Warn("Refusing to disconnect block below the minimum prune depth", "height", blockNum, "pruneHeight", chain.pruneHeight)
+		return nil, false
+	}
+
+	hash := chain.lastBlockHash
+	blk = chain.blocks[hash]
+	undo := chain.undo[hash]
+	chain.disconnectIndexes(blk, hash, undo)
+
+	// Undo transactions in the reverse order they were added
+	chain.removeTransaction(blk.Coinbase, nil)
+	for i := len(blk.Body) - 1; i >= 0; i-- {
+		chain.removeTransaction(blk.Body[i], undo[i])
+		chain.pendingTransactions = append(chain.pendingTransactions, blk.Body[i])
+	}
+
+	delete(chain.blocks, hash)
+	delete(chain.headers, hash)
+	delete(chain.undo, hash)
+	chain.blockBytes -= int64(blk.Size())
+	chain.blockHashes = chain.blockHashes[:blockNum]
+	chain.lastBlockHash = chain.blockHashes[blockNum-1]
+	log.Info("Disconnected block from chain", "hash", hash.Hex(), "height", blockNum, "txs", len(blk.Body))
+
+	return blk, true
+}
+
+// Removes a confirmed transaction from the chain, undoing its effect on the utxo set
+func (chain *Chain) removeTransaction(tx *transaction.Transaction, spent []spentOutput) {
+	revertTransaction(chain.utxos, tx, spent)
+	delete(chain.transactions, tx.Hash())
+}
+
+// Removes a transaction's outputs from a utxo set and restores the outputs it spent
+func revertTransaction(utxos *UtxoSet, tx *transaction.Transaction, spent []spentOutput) {
+	txHash := tx.Hash()
+	for outputIndex := range tx.Outputs {
+		utxos.Remove(transaction.TransactionOutputPointer{
+			TransactionHash: txHash,
+			OutputIndex:     uint16(outputIndex),
+		})
+	}
+	for _, s := range spent {
+		utxos.Add(s.OutputPointer, s.Entry)
+	}
+}
+
+// Starts maintaining an index from transaction hash to the block that confirmed it
+// The index is built from the blocks already in the chain
+// Returns a bool indicating success, which fails if blocks have already been pruned
+func (chain *Chain) EnableTransactionIndex() bool {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	if chain.txIndex != nil {
+		return true
+	}
+	if chain.pruneHeight > 0 {
+		return false
+	}
+	chain.txIndex = newTransactionIndex()
+	for blockNum, hash := range chain.blockHashes {
+		blk := chain.blocks[hash]
+		chain.txIndex.connect(hash, blockNum, blk.Body, blk.Coinbase)
+	}
+
+	return true
+}
+
+// Starts maintaining an index from address to the confirmed transactions that spent from or paid to it
+// The index is built from the blocks already in the chain
+// Returns a bool indicating success, which fails if blocks have already been pruned
+func (chain *Chain) EnableAddressIndex() bool {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	if chain.addrIndex != nil {
+		return true
+	}
+	if chain.pruneHeight > 0 {
+		return false
+	}
+	chain.addrIndex = newAddressIndex()
+	for blockNum, hash := range chain.blockHashes {
+		blk := chain.blocks[hash]
+		undo := chain.undo[hash]
+		for i, tx := range blk.Body {
+			chain.addrIndex.connect(hash, blockNum, tx, undo[i])
+		}
+		chain.addrIndex.connect(hash, blockNum, blk.Coinbase, nil)
+	}
 
 	return true
 }
 
+// Returns true if the address index is enabled
+func (chain *Chain) HasAddressIndex() bool {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.addrIndex != nil
+}
+
+func (chain *Chain) connectIndexes(blk *block.Block, hash common.Hash, blockNum int) {
+	if chain.txIndex != nil {
+		chain.txIndex.connect(hash, blockNum, blk.Body, blk.Coinbase)
+	}
+	if chain.addrIndex != nil {
+		undo := chain.undo[hash]
+		for i, tx := range blk.Body {
+			chain.addrIndex.connect(hash, blockNum, tx, undo[i])
+		}
+		chain.addrIndex.connect(hash, blockNum, blk.Coinbase, nil)
+	}
+}
+
+func (chain *Chain) disconnectIndexes(blk *block.Block, hash common.Hash, undo [][]spentOutput) {
+	if chain.txIndex != nil {
+		chain.txIndex.disconnect(blk.Body, blk.Coinbase)
+	}
+	if chain.addrIndex != nil {
+		addresses := []common.Address{}
+		txs := append([]*transaction.Transaction{blk.Coinbase}, blk.Body...)
+		for _, tx := range txs {
+			for _, output := range tx.Outputs {
+				addresses = append(addresses, output.ReceiverAddress)
+			}
+		}
+		for _, spent := range undo {
+			for _, s := range spent {
+				addresses = append(addresses, s.Entry.Owner)
+			}
+		}
+		chain.addrIndex.disconnect(hash, addresses)
+	}
+}
+
+// Gets the block and position that confirmed a transaction
+// Returns bool indicating success, which fails if the transaction index is disabled or the transaction is not confirmed
+func (chain *Chain) GetTransactionLocation(hash common.Hash) (location TransactionLocation, ok bool) {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	if chain.txIndex == nil {
+		return TransactionLocation{}, false
+	}
+	location, ok = chain.txIndex.locations[hash]
+	return location, ok
+}
+
+// Gets the confirmed transactions that spent from or paid to an address, oldest first
+// Returns bool indicating success, which fails if the address index is disabled
+func (chain *Chain) GetAddressHistory(address common.Address) (history []AddressTransaction, ok bool) {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	if chain.addrIndex == nil {
+		return nil, false
+	}
+
+	return append([]AddressTransaction{}, chain.addrIndex.history[address]...), true
+}
+
+// Given a block number, returns the block at that height
+// Returns bool indicating success, which fails if there is no such block or its body has been pruned
+func (chain *Chain) GetBlockByNumber(blockNum int) (blk *block.Block, ok bool) {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	if blockNum < chain.pruneHeight || blockNum >= len(chain.blockHashes) {
+		return nil, false
+	}
+
+	return chain.blocks[chain.blockHashes[blockNum]], true
+}
+
+// Gets the headers of all blocks starting at the given block number
+// Returns bool indicating success
+func (chain *Chain) GetBlockHeaders(startNum int) (headers []*block.BlockHeader, ok bool) {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	if startNum < 0 {
+		return nil, false
+	}
+
+	headers = []*block.BlockHeader{}
+	for blockNum := startNum; blockNum < len(chain.blockHashes); blockNum++ {
+		headers = append(headers, chain.headers[chain.blockHashes[blockNum]])
+	}
+
+	return headers, true
+}
+
 // Get all unspent output pointers for a given address
+// The returned slice is a copy, so it is safe to use while blocks are added
 // Returns bool indicating success
 func (chain *Chain) GetUnspentTransactions(address common.Address) (outputPointers []*transaction.TransactionOutputPointer, ok bool) {
-	return chain.UnspentOutputs[address], true
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.utxos.GetByAddress(address), true
 }
 
-// Get the output amount corresponding to a specific output pointer
-// Returns bool indicating success
+// Gets the entry of an unspent output
+// Returns bool indicating success, which fails if the output does not exist or has been spent
+func (chain *Chain) GetUtxo(ptr *transaction.TransactionOutputPointer) (entry UtxoEntry, ok bool) {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.utxos.Get(*ptr)
+}
+
+// Returns the number of unspent outputs
+func (chain *Chain) NumUtxos() int {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.utxos.Len()
+}
+
+// Get the output amount corresponding to a specific output pointer, whether or not it has been spent
+// Returns bool indicating success, which fails if the output does not exist
 func (chain *Chain) GetOutputAmount(ptr *transaction.TransactionOutputPointer) (amount uint64, success bool) {
-	hash := ptr.TransactionHash
-	index := ptr.OutputIndex
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.getOutputAmount(ptr)
+}
+
+func (chain *Chain) getOutputAmount(ptr *transaction.TransactionOutputPointer) (amount uint64, success bool) {
+	if entry, ok := chain.utxos.Get(*ptr); ok {
+		return entry.Amount, true
+	}
+
+	// Spent outputs are only found in their transaction
+	tx, ok := chain.transactions[ptr.TransactionHash]
+	if !ok || int(ptr.OutputIndex) >= len(tx.Outputs) {
+		return 0, false
+	}
+
+	return tx.Outputs[ptr.OutputIndex].Amount, true
+}
+
+// Returns true if any confirmed transaction has sent to the given address
+// Without the address index, every stored transaction is scanned, so each call takes time linear in the size of the chain
+// Once blocks are pruned without the address index, an address whose outputs were all spent in pruned blocks
+// is reported as unused, so callers that must find every used address should enable the address index first
+func (chain *Chain) IsAddressUsed(address common.Address) bool {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
 
-	tx := chain.Transactions[hash]
-	output := tx.Outputs[index]
+	if chain.addrIndex != nil {
+		return len(chain.addrIndex.history[address]) > 0
+	}
+	if len(chain.utxos.GetByAddress(address)) > 0 {
+		return true
+	}
+	for _, tx := range chain.transactions {
+		for _, output := range tx.Outputs {
+			if output.ReceiverAddress.Equal(address) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns true if an output can be spent in the next block
+// Outputs of coinbase transactions can only be spent once they have enough confirmations
+func (chain *Chain) IsOutputMature(ptr *transaction.TransactionOutputPointer) bool {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.isOutputMature(ptr)
+}
+
+func (chain *Chain) isOutputMature(ptr *transaction.TransactionOutputPointer) bool {
+	entry, ok := chain.utxos.Get(*ptr)
+	if !ok || !entry.IsCoinbase {
+		return true
+	}
 
-	return output.Amount, true
+	_, lastBlockNum, _ := chain.getLastBlockInfo()
+	return chain.params.IsCoinbaseMature(entry.BlockNum, lastBlockNum+1)
 }
 
-// Gets the value of an account based on an address
+// Gets the value of an account based on an address, including immature coinbase outputs
 func (chain *Chain) GetAccountValue(address common.Address) uint64 {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.getAccountValue(address)
+}
+
+func (chain *Chain) getAccountValue(address common.Address) uint64 {
+	var total uint64 = 0
+	for _, ptr := range chain.utxos.GetByAddress(address) {
+		entry, _ := chain.utxos.Get(*ptr)
+		total += entry.Amount
+	}
+
+	return total
+}
+
+// Gets the value of an account's coinbase outputs that cannot be spent yet
+func (chain *Chain) GetImmatureAccountValue(address common.Address) uint64 {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
 	var total uint64 = 0
-	outputPointers, _ := chain.GetUnspentTransactions(address)
-	for _, ptr := range outputPointers {
-		amount, _ := chain.GetOutputAmount(ptr)
-		total += amount
+	for _, ptr := range chain.utxos.GetByAddress(address) {
+		if !chain.isOutputMature(ptr) {
+			entry, _ := chain.utxos.Get(*ptr)
+			total += entry.Amount
+		}
 	}
 
 	return total
 }
 
+// Gets the total value of all unspent outputs, which is the supply of coins in circulation
+func (chain *Chain) GetCirculatingSupply() uint64 {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.utxos.TotalValue()
+}
+
 // Prints the current state of the blockchain
 func (chain *Chain) PrintChainState() {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
 	fmt.Printf("-------------------PRINTING CHAIN STATE-------------------\n")
 	fmt.Printf("Blocks mined...\n")
-	for _, block := range chain.Blocks {
+	for _, block := range chain.blocks {
 		fmt.Printf("Block: %v\n", block.Hash().Hex())
 	}
 
 	fmt.Printf("Transactions confirmed...\n")
-	for _, tx := range chain.Transactions {
+	for _, tx := range chain.transactions {
 		fmt.Printf("Transaction: %v\n", tx.Hash().Hex())
 	}
 
 	fmt.Printf("Unspent transactions...\n")
-	for address, outputList := range chain.UnspentOutputs {
-		amount := util.Uint64UnitToFloat64Unit(chain.GetAccountValue(address))
-		fmt.Printf("Account %v has value %v\n", address.Hex(), amount)
+	for _, address := range chain.utxos.Addresses() {
+		outputList := chain.utxos.GetByAddress(address)
+		amount := util.Amount(chain.getAccountValue(address))
+		fmt.Printf("Account %v has value %v\n", chain.params.EncodeAddress(address), amount)
 		for _, output := range outputList {
 			fmt.Printf("Account %v has unspent output at transaction %v index %v\n",
-				address.Hex(),
+				chain.params.EncodeAddress(address),
 				output.TransactionHash.Hex(),
 				output.OutputIndex,
 			)
 		}
 	}
 
-	fmt.Printf("There are %v pending trnasactions\n", len(chain.PendingTransactions))
+	fmt.Printf("There are %v pending trnasactions\n", len(chain.pendingTransactions))
 	fmt.Printf("-------------------END CHAIN STATE-------------------\n")
 }
-
-// Shallow copies chain over into another chain
-func (chain *Chain) UnsafeCopy() *Chain {
-	otherChain, _ := New()
-	for blockHash, block := range chain.Blocks {
-		otherChain.Blocks[blockHash] = block
-	}
-	for txHash, tx := range chain.Transactions {
-		otherChain.Transactions[txHash] = tx
-	}
-	otherChain.LastBlockHash = chain.LastBlockHash
-	for _, tx := range chain.PendingTransactions {
-		otherChain.PendingTransactions = append(otherChain.PendingTransactions, tx)
-	}
-	for address, utxos := range chain.UnspentOutputs {
-		newUtxos := []*transaction.TransactionOutputPointer{}
-		for _, utxo := range utxos {
-			newUtxos = append(newUtxos, utxo)
-		}
-		otherChain.UnspentOutputs[address] = newUtxos
-	}
-
-	return otherChain
-}
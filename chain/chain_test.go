@@ -0,0 +1,133 @@
+package chain
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// Creates a transaction with no inputs paying amount to address
+func newTestTransaction(address common.Address, amount uint64) *transaction.Transaction {
+	output := &transaction.TransactionOutput{ReceiverAddress: address, Amount: amount}
+	tx, _ := transaction.New(
+		[]*transaction.TransactionInput{},
+		[]*transaction.TransactionOutput{output},
+	)
+
+	return tx
+}
+
+// Creates a block on top of prevHash whose coinbase pays amount to address
+func newTestBlock(prevHash common.Hash, blockNum int, address common.Address, amount uint64) *block.Block {
+	blk, _ := block.New(prevHash, blockNum, []*transaction.Transaction{}, newTestTransaction(address, amount))
+
+	return blk
+}
+
+// Tests that queries, pending pool updates and copies can run while blocks are added
+// Run with -race to detect unsynchronized access
+func TestConcurrentAccess(t *testing.T) {
+	const numBlocks = 50
+	const numReaders = 4
+	address := common.BytesToAddress([]byte{1})
+	chn, _ := New(&protocol.DevNetParams)
+	chn.Initialize(newTestBlock(common.Hash{}, 0, address, 1))
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Add blocks on top of the tip
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 1; i <= numBlocks; i++ {
+			hash, blockNum, _ := chn.GetLastBlockInfo()
+			if !chn.AddBlock(newTestBlock(hash, blockNum+1, address, uint64(i+1))) {
+				t.Errorf(`Failed to add block %v`, i)
+				return
+			}
+		}
+	}()
+
+	// Churn the pending pool
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numBlocks; i++ {
+			tx := newTestTransaction(address, uint64(1000+i))
+			chn.AddPendingTransaction(tx)
+			chn.GetPendingTransactions(chn.NumPendingTransactions())
+			chn.RemovePendingTransactions([]*transaction.Transaction{tx})
+		}
+	}()
+
+	// Query the chain until all blocks are added
+	for r := 0; r < numReaders; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				utxos, _ := chn.GetUnspentTransactions(address)
+				for _, ptr := range utxos {
+					if _, ok := chn.GetOutputAmount(ptr); !ok {
+						t.Errorf(`Unspent output is missing its transaction`)
+						return
+					}
+				}
+				_, blockNum, _ := chn.GetLastBlockInfo()
+				if headers, _ := chn.GetBlockHeaders(0); len(headers) < blockNum+1 {
+					t.Errorf(`Got %v headers for a chain of height %v`, len(headers), blockNum)
+					return
+				}
+				chn.GetAccountValue(address)
+				chn.GetImmatureAccountValue(address)
+				chn.GetCirculatingSupply()
+				chn.IsAddressUsed(address)
+				chn.NewView().GetUtxo(&transaction.TransactionOutputPointer{})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	_, blockNum, _ := chn.GetLastBlockInfo()
+	if blockNum != numBlocks {
+		t.Fatalf(`Expected height %v, got %v`, numBlocks, blockNum)
+	}
+	expectedValue := uint64((numBlocks + 1) * (numBlocks + 2) / 2)
+	if value := chn.GetAccountValue(address); value != expectedValue {
+		t.Fatalf(`Expected account value %v, got %v`, expectedValue, value)
+	}
+	if chn.NumPendingTransactions() != 0 {
+		t.Fatalf(`Expected an empty pending pool, got %v transactions`, chn.NumPendingTransactions())
+	}
+}
+
+// Tests that a block built on a tip that has since moved is not added
+func TestAddBlockRejectsStaleParent(t *testing.T) {
+	address := common.BytesToAddress([]byte{1})
+	chn, _ := New(&protocol.DevNetParams)
+	chn.Initialize(newTestBlock(common.Hash{}, 0, address, 1))
+	genesisHash, _, _ := chn.GetLastBlockInfo()
+
+	if !chn.AddBlock(newTestBlock(genesisHash, 1, address, 2)) {
+		t.Fatalf(`Failed to add a block on the tip`)
+	}
+	if chn.AddBlock(newTestBlock(genesisHash, 1, address, 3)) {
+		t.Fatalf(`Added a block whose parent is no longer the tip`)
+	}
+	if chn.Initialize(newTestBlock(common.Hash{}, 0, address, 4)) {
+		t.Fatalf(`Initialized a chain that already has blocks`)
+	}
+}
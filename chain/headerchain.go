@@ -0,0 +1,97 @@
+package chain
+
+import (
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// A header chain is the state kept by a light client, which stores only block headers
+// and the transactions relevant to its accounts that have been proven to be in a block
+type HeaderChain struct {
+	Headers        map[common.Hash]*block.BlockHeader
+	HeaderHashes   []common.Hash // Header hashes indexed by block number
+	LastHeaderHash common.Hash
+	Transactions   map[common.Hash]*transaction.Transaction // Verified transactions relevant to this client
+}
+
+// Sets up the initial state of the header chain
+func NewHeaderChain() (hc *HeaderChain, ok bool) {
+	headerChain := HeaderChain{
+		Headers:        make(map[common.Hash]*block.BlockHeader),
+		HeaderHashes:   []common.Hash{},
+		LastHeaderHash: *new(common.Hash),
+		Transactions:   make(map[common.Hash]*transaction.Transaction),
+	}
+
+	return &headerChain, true
+}
+
+// Initializes the header chain with a trusted genesis header, return a bool indicating success
+func (hc *HeaderChain) Initialize(genesisHeader *block.BlockHeader) bool {
+	return hc.AddHeader(genesisHeader)
+}
+
+// Get information about the last header in the chain
+// Gets the hash and block number of the last header
+// Returns bool indicating success
+func (hc *HeaderChain) GetLastHeaderInfo() (hash common.Hash, blockNum int, ok bool) {
+	if len(hc.HeaderHashes) == 0 {
+		return hash, -1, false
+	}
+
+	return hc.LastHeaderHash, len(hc.HeaderHashes) - 1, true
+}
+
+// Given a block hash, returns the corresponding header
+// Returns bool indicating if the header is known
+func (hc *HeaderChain) GetHeader(hash common.Hash) (header *block.BlockHeader, ok bool) {
+	header, ok = hc.Headers[hash]
+	return header, ok
+}
+
+// Adds a header to the tip of the chain
+// Returns bool indicating success
+// This is not a smart function - it will add the header without validation
+func (hc *HeaderChain) AddHeader(header *block.BlockHeader) bool {
+	hash := header.Hash()
+	hc.Headers[hash] = header
+	hc.HeaderHashes = append(hc.HeaderHashes, hash)
+	hc.LastHeaderHash = hash
+
+	return true
+}
+
+// Adds a transaction that has been proven to be included in a block of this chain
+// Returns bool indicating success
+// This is not a smart function - it will add the transaction without verifying its inclusion proof
+func (hc *HeaderChain) AddVerifiedTransaction(tx *transaction.Transaction) bool {
+	hc.Transactions[tx.Hash()] = tx
+
+	return true
+}
+
+// Gets the value of an account based on the verified transactions sending to and from it
+func (hc *HeaderChain) GetAccountValue(address common.Address) uint64 {
+	spent := make(map[transaction.TransactionOutputPointer]bool)
+	for _, tx := range hc.Transactions {
+		for _, input := range tx.Inputs {
+			spent[*input.OutputPointer] = true
+		}
+	}
+
+	var total uint64 = 0
+	for txHash, tx := range hc.Transactions {
+		for outputIndex, output := range tx.Outputs {
+			ptr := transaction.TransactionOutputPointer{
+				TransactionHash: txHash,
+				OutputIndex:     uint16(outputIndex),
+			}
+			if output.ReceiverAddress.Equal(address) && !spent[ptr] {
+				total += output.Amount
+			}
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,133 @@
+package chain
+
+import (
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// The location of a confirmed transaction in the chain
+type TransactionLocation struct {
+	BlockHash common.Hash
+	BlockNum  int
+	Position  int // The index of the transaction in the block body, or -1 for the coinbase
+}
+
+const CoinbasePosition = -1 // The position of a block's coinbase in a transaction location
+
+// Whether a transaction added to or took value from an address
+type Direction int
+
+const (
+	DirectionReceive Direction = iota // The address received more than it spent
+	DirectionSend                     // The address spent at least as much as it received
+)
+
+func (d Direction) String() string {
+	if d == DirectionReceive {
+		return "receive"
+	}
+
+	return "send"
+}
+
+// A confirmed transaction that spent from or paid to an address
+type AddressTransaction struct {
+	TransactionHash common.Hash
+	BlockHash       common.Hash
+	BlockNum        int
+	Direction       Direction
+	Amount          uint64 // The size of the net change to the address's value
+}
+
+// A spent output records an output a block spent, so the block can be disconnected
+type spentOutput struct {
+	OutputPointer transaction.TransactionOutputPointer
+	Entry         UtxoEntry
+}
+
+// A transaction index maps transaction hashes to where they were confirmed
+type transactionIndex struct {
+	locations map[common.Hash]TransactionLocation
+}
+
+func newTransactionIndex() *transactionIndex {
+	return &transactionIndex{locations: make(map[common.Hash]TransactionLocation)}
+}
+
+func (ti *transactionIndex) connect(blockHash common.Hash, blockNum int, body []*transaction.Transaction, coinbase *transaction.Transaction) {
+	for position, tx := range body {
+		ti.locations[tx.Hash()] = TransactionLocation{BlockHash: blockHash, BlockNum: blockNum, Position: position}
+	}
+	ti.locations[coinbase.Hash()] = TransactionLocation{BlockHash: blockHash, BlockNum: blockNum, Position: CoinbasePosition}
+}
+
+func (ti *transactionIndex) disconnect(body []*transaction.Transaction, coinbase *transaction.Transaction) {
+	for _, tx := range body {
+		delete(ti.locations, tx.Hash())
+	}
+	delete(ti.locations, coinbase.Hash())
+}
+
+// An address index maps addresses to the confirmed transactions that touched them, oldest first
+type addressIndex struct {
+	history map[common.Address][]AddressTransaction
+}
+
+func newAddressIndex() *addressIndex {
+	return &addressIndex{history: make(map[common.Address][]AddressTransaction)}
+}
+
+// Records a transaction against every address it spent from or paid to
+// spent holds the outputs the transaction spent
+func (ai *addressIndex) connect(blockHash common.Hash, blockNum int, tx *transaction.Transaction, spent []spentOutput) {
+	sent := make(map[common.Address]uint64)
+	received := make(map[common.Address]uint64)
+	addresses := []common.Address{}
+	seen := make(map[common.Address]bool)
+	for _, s := range spent {
+		sent[s.Entry.Owner] += s.Entry.Amount
+		if !seen[s.Entry.Owner] {
+			seen[s.Entry.Owner] = true
+			addresses = append(addresses, s.Entry.Owner)
+		}
+	}
+	for _, output := range tx.Outputs {
+		received[output.ReceiverAddress] += output.Amount
+		if !seen[output.ReceiverAddress] {
+			seen[output.ReceiverAddress] = true
+			addresses = append(addresses, output.ReceiverAddress)
+		}
+	}
+
+	txHash := tx.Hash()
+	for _, address := range addresses {
+		entry := AddressTransaction{
+			TransactionHash: txHash,
+			BlockHash:       blockHash,
+			BlockNum:        blockNum,
+			Direction:       DirectionReceive,
+			Amount:          received[address] - sent[address],
+		}
+		if sent[address] >= received[address] {
+			entry.Direction = DirectionSend
+			entry.Amount = sent[address] - received[address]
+		}
+		ai.history[address] = append(ai.history[address], entry)
+	}
+}
+
+// Removes every entry recorded for a block, which are the newest entries of each address it touched
+func (ai *addressIndex) disconnect(blockHash common.Hash, addresses []common.Address) {
+	for _, address := range addresses {
+		history := ai.history[address]
+		end := len(history)
+		for end > 0 && history[end-1].BlockHash.Equal(blockHash) {
+			end--
+		}
+		if end == 0 {
+			delete(ai.history, address)
+		} else {
+			ai.history[address] = history[:end]
+		}
+	}
+}
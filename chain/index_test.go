@@ -0,0 +1,94 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/account"
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// Builds a chain where alice mines the genesis block and then pays bob 60 of its 100 in block 1
+func newTestIndexChain(t *testing.T) (chn *Chain, alice *account.Account, bob common.Address, payment *transaction.Transaction) {
+	alice, _ = account.New()
+	bob = common.BytesToAddress([]byte{2})
+	chn, _ = New(&protocol.DevNetParams)
+	genesis := newTestBlock(common.Hash{}, 0, alice.Address, 100)
+	chn.Initialize(genesis)
+
+	genesisPtr := &transaction.TransactionOutputPointer{TransactionHash: genesis.Coinbase.Hash()}
+	payment = newTestSpend(alice, genesisPtr, 30)
+	payment, _ = transaction.New(payment.Inputs, []*transaction.TransactionOutput{
+		{ReceiverAddress: bob, Amount: 60},
+		{ReceiverAddress: alice.Address, Amount: 30},
+	})
+	blk, _ := block.New(genesis.Hash(), 1, []*transaction.Transaction{payment}, newTestTransaction(bob, 10))
+	if !chn.AddBlock(blk) {
+		t.Fatalf(`Failed to add block`)
+	}
+
+	return chn, alice, bob, payment
+}
+
+// Tests that the indexes are built from existing blocks and follow blocks as they are connected and disconnected
+func TestIndexes(t *testing.T) {
+	chn, alice, bob, payment := newTestIndexChain(t)
+	if _, ok := chn.GetTransactionLocation(payment.Hash()); ok {
+		t.Fatalf(`Found a transaction location with the index disabled`)
+	}
+	if _, ok := chn.GetAddressHistory(bob); ok {
+		t.Fatalf(`Found address history with the index disabled`)
+	}
+
+	chn.EnableTransactionIndex()
+	chn.EnableAddressIndex()
+	tip, _, _ := chn.GetLastBlockInfo()
+	if location, ok := chn.GetTransactionLocation(payment.Hash()); !ok || location.BlockNum != 1 || location.Position != 0 || !location.BlockHash.Equal(tip) {
+		t.Fatalf(`Got unexpected location %+v for the payment`, location)
+	}
+
+	// Alice mined 100 and then sent a net 70, bob received 60 and a coinbase of 10
+	aliceHistory, _ := chn.GetAddressHistory(alice.Address)
+	if len(aliceHistory) != 2 || aliceHistory[0].Direction != DirectionReceive || aliceHistory[0].Amount != 100 ||
+		aliceHistory[1].Direction != DirectionSend || aliceHistory[1].Amount != 70 {
+		t.Fatalf(`Got unexpected history for alice: %+v`, aliceHistory)
+	}
+	bobHistory, _ := chn.GetAddressHistory(bob)
+	if len(bobHistory) != 2 || bobHistory[0].Amount != 60 || bobHistory[1].Amount != 10 || bobHistory[1].BlockNum != 1 {
+		t.Fatalf(`Got unexpected history for bob: %+v`, bobHistory)
+	}
+
+	// Connecting a block updates the indexes
+	next := newTestBlock(tip, 2, bob, 5)
+	chn.AddBlock(next)
+	if location, ok := chn.GetTransactionLocation(next.Coinbase.Hash()); !ok || location.BlockNum != 2 || location.Position != CoinbasePosition {
+		t.Fatalf(`Got unexpected location %+v for a new coinbase`, location)
+	}
+	if bobHistory, _ := chn.GetAddressHistory(bob); len(bobHistory) != 3 {
+		t.Fatalf(`Expected 3 entries for bob, got %v`, len(bobHistory))
+	}
+
+	// Disconnecting blocks rolls back the indexes and the utxos
+	chn.DisconnectTip()
+	chn.DisconnectTip()
+	if _, ok := chn.GetTransactionLocation(payment.Hash()); ok {
+		t.Fatalf(`Disconnected transaction is still indexed`)
+	}
+	if bobHistory, _ := chn.GetAddressHistory(bob); len(bobHistory) != 0 {
+		t.Fatalf(`Expected no history for bob, got %+v`, bobHistory)
+	}
+	if aliceHistory, _ := chn.GetAddressHistory(alice.Address); len(aliceHistory) != 1 {
+		t.Fatalf(`Expected only the genesis coinbase for alice, got %+v`, aliceHistory)
+	}
+	if chn.GetAccountValue(alice.Address) != 100 || chn.GetAccountValue(bob) != 0 || chn.GetCirculatingSupply() != 100 {
+		t.Fatalf(`Disconnecting did not restore the utxos`)
+	}
+	if chn.NumPendingTransactions() != 1 {
+		t.Fatalf(`Expected the disconnected payment to return to the pending pool`)
+	}
+	if _, ok := chn.DisconnectTip(); ok {
+		t.Fatalf(`Disconnected the genesis block`)
+	}
+}
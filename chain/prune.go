@@ -0,0 +1,80 @@
+package chain
+
+// The fewest recent blocks a pruned chain keeps in full, so short reorganizations can still be disconnected
+const MinPruneDepth = 6
+
+// Pruning settings decide which old block bodies and undo data a chain deletes
+// Headers and the utxo set are always kept
+type PruneConfig struct {
+	Depth    int   // Keep the bodies of this many blocks at the tip, or 0 for no depth limit
+	MaxBytes int64 // Keep at most this many bytes of full blocks, or 0 for no size limit
+}
+
+// Starts pruning old blocks with the given settings, pruning immediately and then as blocks are added
+// Depths below MinPruneDepth are raised to it
+// Returns a bool indicating success, which fails if the settings have neither a depth nor a size limit
+func (chain *Chain) EnablePruning(config PruneConfig) bool {
+	if config.Depth <= 0 && config.MaxBytes <= 0 {
+		return false
+	}
+	if config.Depth > 0 && config.Depth < MinPruneDepth {
+		config.Depth = MinPruneDepth
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.pruning = &config
+	chain.prune()
+
+	return true
+}
+
+// Returns the number of the oldest block that still has its body
+// Blocks below this height only have their headers
+func (chain *Chain) PruneHeight() int {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return chain.pruneHeight
+}
+
+// Returns true if the block with the given number exists but its body has been pruned
+func (chain *Chain) IsBlockPruned(blockNum int) bool {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	return blockNum >= 0 && blockNum < chain.pruneHeight
+}
+
+// Deletes the bodies, transactions and undo data of the oldest blocks until the pruning limits are met
+func (chain *Chain) prune() {
+	if chain.pruning == nil {
+		return
+	}
+
+	start := chain.pruneHeight
+	for {
+		kept := len(chain.blockHashes) - chain.pruneHeight
+		overDepth := chain.pruning.Depth > 0 && kept > chain.pruning.Depth
+		overSize := chain.pruning.MaxBytes > 0 && chain.blockBytes > chain.pruning.MaxBytes
+		if kept <= MinPruneDepth || (!overDepth && !overSize) {
+			break
+		}
+
+		hash := chain.blockHashes[chain.pruneHeight]
+		blk := chain.blocks[hash]
+		for _, tx := range blk.Body {
+			delete(chain.transactions, tx.Hash())
+		}
+		delete(chain.transactions, blk.Coinbase.Hash())
+		delete(chain.blocks, hash)
+		delete(chain.undo, hash)
+		chain.blockBytes -= int64(blk.Size())
+		chain.pruneHeight++
+	}
+
+	if chain.pruneHeight > start {
+		log.Debug("Pruned blocks", "from", start, "to", chain.pruneHeight-1, "bytes", chain.blockBytes)
+	}
+}
@@ -0,0 +1,91 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+)
+
+// Builds a chain of numBlocks blocks whose coinbases each pay 1 to address
+func newTestPruneChain(address common.Address, numBlocks int) *Chain {
+	chn, _ := New(&protocol.DevNetParams)
+	chn.Initialize(newTestBlock(common.Hash{}, 0, address, 1))
+	for blockNum := 1; blockNum < numBlocks; blockNum++ {
+		tip, _, _ := chn.GetLastBlockInfo()
+		chn.AddBlock(newTestBlock(tip, blockNum, address, 1))
+	}
+
+	return chn
+}
+
+// Tests that pruning by depth drops old bodies while keeping headers and the utxo set
+func TestPruneDepth(t *testing.T) {
+	address := common.BytesToAddress([]byte{1})
+	chn := newTestPruneChain(address, 20)
+	oldBlock, _ := chn.GetBlockByNumber(0)
+
+	if chn.EnablePruning(PruneConfig{}) {
+		t.Fatalf(`Enabled pruning without any limit`)
+	}
+	chn.EnablePruning(PruneConfig{Depth: 10})
+	if chn.PruneHeight() != 10 {
+		t.Fatalf(`Expected prune height 10, got %v`, chn.PruneHeight())
+	}
+	if _, ok := chn.GetBlockByNumber(9); ok || !chn.IsBlockPruned(9) {
+		t.Fatalf(`Got the body of a pruned block`)
+	}
+	if _, ok := chn.GetBlockByNumber(10); !ok || chn.IsBlockPruned(10) {
+		t.Fatalf(`Failed to get the body of a recent block`)
+	}
+	if _, ok := chn.GetTransaction(oldBlock.Coinbase.Hash()); ok {
+		t.Fatalf(`Pruned transaction is still stored`)
+	}
+	if headers, _ := chn.GetBlockHeaders(0); len(headers) != 20 || !headers[0].Hash().Equal(oldBlock.Hash()) {
+		t.Fatalf(`Pruning dropped block headers`)
+	}
+	if chn.GetAccountValue(address) != 20 || !chn.IsAddressUsed(address) {
+		t.Fatalf(`Pruning changed the utxo set`)
+	}
+
+	// New blocks keep the depth
+	tip, _, _ := chn.GetLastBlockInfo()
+	chn.AddBlock(newTestBlock(tip, 20, address, 1))
+	if chn.PruneHeight() != 11 {
+		t.Fatalf(`Expected prune height 11 after adding a block, got %v`, chn.PruneHeight())
+	}
+
+	// Recent blocks can still be disconnected, but never leaving fewer than MinPruneDepth full blocks
+	for i := 0; i < 10-MinPruneDepth; i++ {
+		if _, ok := chn.DisconnectTip(); !ok {
+			t.Fatalf(`Failed to disconnect an unpruned block`)
+		}
+	}
+	if _, ok := chn.DisconnectTip(); ok {
+		t.Fatalf(`Disconnected a block below the minimum prune depth`)
+	}
+	if _, lastBlockNum, _ := chn.GetLastBlockInfo(); lastBlockNum-chn.PruneHeight()+1 != MinPruneDepth {
+		t.Fatalf(`Expected %v full blocks to be kept, got %v`, MinPruneDepth, lastBlockNum-chn.PruneHeight()+1)
+	}
+	if chn.EnableTransactionIndex() || chn.EnableAddressIndex() {
+		t.Fatalf(`Built an index over pruned blocks`)
+	}
+}
+
+// Tests that pruning by size keeps at least MinPruneDepth blocks
+func TestPruneMaxBytes(t *testing.T) {
+	address := common.BytesToAddress([]byte{1})
+	chn := newTestPruneChain(address, 20)
+	blk, _ := chn.GetBlockByNumber(0)
+	size := int64(blk.Size())
+
+	chn.EnablePruning(PruneConfig{MaxBytes: 8 * size})
+	if chn.PruneHeight() != 12 {
+		t.Fatalf(`Expected prune height 12, got %v`, chn.PruneHeight())
+	}
+
+	chn.EnablePruning(PruneConfig{MaxBytes: 1})
+	if chn.PruneHeight() != 20-MinPruneDepth {
+		t.Fatalf(`Expected %v blocks to be kept, got %v`, MinPruneDepth, 20-chn.PruneHeight())
+	}
+}
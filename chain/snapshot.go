@@ -0,0 +1,217 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+const (
+	SnapshotVersion = 1 // The version of the snapshot format written by this node
+
+	snapshotEntryLength = transaction.TransactionOutputPointerLength + 8 + common.AddressLength + 4 + 1 // The number of bytes in a utxo entry
+)
+
+var snapshotMagic = []byte("TCSNAP") // The bytes every snapshot file starts with
+
+// Reasons a snapshot cannot be loaded
+var (
+	ErrSnapshotMalformed = errors.New("snapshot is malformed")
+	ErrSnapshotVersion   = errors.New("snapshot version is not supported")
+	ErrSnapshotHash      = errors.New("snapshot content does not match its hash")
+	ErrSnapshotUntrusted = errors.New("snapshot hash is not pinned in the chain parameters")
+	ErrSnapshotChain     = errors.New("chain already has blocks")
+)
+
+// Describes a snapshot of the utxo set taken at a block
+type SnapshotInfo struct {
+	Version   uint16
+	BlockNum  int
+	BlockHash common.Hash
+	NumUtxos  int
+	Hash      common.Hash // The hash of the snapshot's content, which is pinned in chain parameters to trust it
+}
+
+// A snapshot is laid out as:
+// magic | version | block number | header count | (header length | header)... | utxo count | (outpoint | amount | owner | block number | coinbase)... | content hash
+// Utxos are sorted by outpoint so the same utxo set always produces the same content hash
+
+// Writes a snapshot of the utxo set as of the block with the given number, along with the headers up to it
+// Returns the snapshot's description and a bool indicating success,
+// which fails if there is no such block or a block after it has been pruned
+func (chain *Chain) ExportSnapshot(w io.Writer, blockNum int) (info SnapshotInfo, ok bool) {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	tipNum := len(chain.blockHashes) - 1
+	if blockNum < 0 || blockNum > tipNum || blockNum+1 < chain.pruneHeight {
+		return SnapshotInfo{}, false
+	}
+
+	// Undo the blocks after the snapshot's block on a copy of the utxo set
+	utxos := chain.utxos
+	if blockNum < tipNum {
+		utxos = chain.utxos.Copy()
+		for n := tipNum; n > blockNum; n-- {
+			hash := chain.blockHashes[n]
+			blk := chain.blocks[hash]
+			undo := chain.undo[hash]
+			revertTransaction(utxos, blk.Coinbase, nil)
+			for i := len(blk.Body) - 1; i >= 0; i-- {
+				revertTransaction(utxos, blk.Body[i], undo[i])
+			}
+		}
+	}
+
+	content := [][]byte{
+		snapshotMagic,
+		util.Uint16ToBytes(SnapshotVersion),
+		util.Uint32ToBytes(uint32(blockNum)),
+		util.Uint32ToBytes(uint32(blockNum + 1)),
+	}
+	for n := 0; n <= blockNum; n++ {
+		headerBytes := chain.headers[chain.blockHashes[n]].Bytes()
+		content = append(content, util.Uint16ToBytes(uint16(len(headerBytes))), headerBytes)
+	}
+
+	ptrs := utxos.Outpoints()
+	content = append(content, util.Uint64ToBytes(uint64(len(ptrs))))
+	for i := range ptrs {
+		entry := utxos.entries[ptrs[i]]
+		coinbaseByte := []byte{0}
+		if entry.IsCoinbase {
+			coinbaseByte = []byte{1}
+		}
+		content = append(content,
+			ptrs[i].Bytes(),
+			util.Uint64ToBytes(entry.Amount),
+			entry.Owner.Bytes(),
+			util.Uint32ToBytes(uint32(entry.BlockNum)),
+			coinbaseByte,
+		)
+	}
+
+	contentBytes := util.ConcatByteSlices(content)
+	info = SnapshotInfo{
+		Version:   SnapshotVersion,
+		BlockNum:  blockNum,
+		BlockHash: chain.blockHashes[blockNum],
+		NumUtxos:  len(ptrs),
+		Hash:      crypto.HashBytes(contentBytes),
+	}
+	if _, err := w.Write(contentBytes); err != nil {
+		return SnapshotInfo{}, false
+	}
+	if _, err := w.Write(info.Hash.Bytes()); err != nil {
+		return SnapshotInfo{}, false
+	}
+
+	return info, true
+}
+
+// Reads consecutive fields from a snapshot, remembering if it ran out of bytes
+type snapshotReader struct {
+	data []byte
+	pos  int
+	ok   bool
+}
+
+func (r *snapshotReader) next(n int) []byte {
+	if !r.ok || n < 0 || len(r.data)-r.pos < n {
+		r.ok = false
+		return make([]byte, n)
+	}
+	field := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return field
+}
+
+// Starts an empty chain from a snapshot, trusting it only if its hash is pinned in the chain parameters
+// The chain keeps the snapshot's headers and utxo set, and every block up to the snapshot's block is treated as pruned
+// Returns the snapshot's description, or an error explaining why it was refused
+func (chain *Chain) LoadSnapshot(r io.Reader) (info SnapshotInfo, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	if len(data) < len(snapshotMagic)+2+common.HashLength || !bytes.Equal(data[:len(snapshotMagic)], snapshotMagic) {
+		return SnapshotInfo{}, ErrSnapshotMalformed
+	}
+
+	contentBytes := data[:len(data)-common.HashLength]
+	info.Hash = common.BytesToHash(data[len(contentBytes):])
+	if !crypto.HashBytes(contentBytes).Equal(info.Hash) {
+		return SnapshotInfo{}, ErrSnapshotHash
+	}
+
+	reader := snapshotReader{data: contentBytes, pos: len(snapshotMagic), ok: true}
+	info.Version = util.BytesToUint16(reader.next(2))
+	if info.Version != SnapshotVersion {
+		return SnapshotInfo{}, ErrSnapshotVersion
+	}
+	info.BlockNum = int(util.BytesToUint32(reader.next(4)))
+	if !chain.params.IsSnapshotTrusted(info.BlockNum, info.Hash) {
+		return SnapshotInfo{}, ErrSnapshotUntrusted
+	}
+
+	// Headers must link from the genesis block to the snapshot's block
+	numHeaders := int(util.BytesToUint32(reader.next(4)))
+	if numHeaders != info.BlockNum+1 {
+		return SnapshotInfo{}, ErrSnapshotMalformed
+	}
+	headers := make(map[common.Hash]*block.BlockHeader)
+	blockHashes := []common.Hash{}
+	for n := 0; n < numHeaders && reader.ok; n++ {
+		headerLength := int(util.BytesToUint16(reader.next(block.BlockHeaderLengthLength)))
+		header, headerOk := block.BytesToBlockHeader(reader.next(headerLength))
+		if !headerOk || (n > 0 && !header.PreviousBlockHash.Equal(blockHashes[n-1])) {
+			return SnapshotInfo{}, ErrSnapshotMalformed
+		}
+		hash := header.Hash()
+		headers[hash] = header
+		blockHashes = append(blockHashes, hash)
+	}
+
+	utxos := NewUtxoSet()
+	numUtxos := util.BytesToUint64(reader.next(8))
+	if !reader.ok || numUtxos > uint64(len(contentBytes)-reader.pos)/snapshotEntryLength {
+		return SnapshotInfo{}, ErrSnapshotMalformed
+	}
+	for i := uint64(0); i < numUtxos; i++ {
+		ptr := transaction.BytesToTransactionOutputPointer(reader.next(transaction.TransactionOutputPointerLength))
+		utxos.Add(*ptr, UtxoEntry{
+			Amount:     util.BytesToUint64(reader.next(8)),
+			Owner:      common.BytesToAddress(reader.next(common.AddressLength)),
+			BlockNum:   int(util.BytesToUint32(reader.next(4))),
+			IsCoinbase: reader.next(1)[0] == 1,
+		})
+	}
+	if !reader.ok || reader.pos != len(contentBytes) {
+		return SnapshotInfo{}, ErrSnapshotMalformed
+	}
+	info.BlockHash = blockHashes[info.BlockNum]
+	info.NumUtxos = utxos.Len()
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	if len(chain.blockHashes) != 0 {
+		return SnapshotInfo{}, ErrSnapshotChain
+	}
+	chain.headers = headers
+	chain.blockHashes = blockHashes
+	chain.lastBlockHash = info.BlockHash
+	chain.utxos = utxos
+	chain.pruneHeight = info.BlockNum + 1
+	log.Info("Loaded utxo snapshot", "hash", info.Hash.Hex(), "height", info.BlockNum, "utxos", info.NumUtxos)
+
+	return info, nil
+}
@@ -0,0 +1,83 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+)
+
+// Tests that a snapshot restores the utxo set and headers it was taken with
+func TestSnapshot(t *testing.T) {
+	chn, alice, bob, _ := newTestIndexChain(t)
+	tip, _, _ := chn.GetLastBlockInfo()
+
+	var buf bytes.Buffer
+	info, ok := chn.ExportSnapshot(&buf, 1)
+	if !ok || info.BlockNum != 1 || !info.BlockHash.Equal(tip) || info.NumUtxos != 3 {
+		t.Fatalf(`Got unexpected snapshot %+v`, info)
+	}
+	data := buf.Bytes()
+
+	// The snapshot is refused until its hash is pinned
+	untrusted, _ := New(&protocol.DevNetParams)
+	if _, err := untrusted.LoadSnapshot(bytes.NewReader(data)); !errors.Is(err, ErrSnapshotUntrusted) {
+		t.Fatalf(`Expected an untrusted snapshot error, got %v`, err)
+	}
+
+	params := protocol.DevNetParams
+	params.SnapshotHashes = map[int]common.Hash{1: info.Hash}
+	loaded, _ := New(&params)
+	if _, err := loaded.LoadSnapshot(bytes.NewReader(data)); err != nil {
+		t.Fatalf(`Failed to load snapshot: %v`, err)
+	}
+	if hash, blockNum, _ := loaded.GetLastBlockInfo(); !hash.Equal(tip) || blockNum != 1 {
+		t.Fatalf(`Loaded chain has tip %v at height %v`, hash.Hex(), blockNum)
+	}
+	if loaded.GetAccountValue(alice.Address) != 30 || loaded.GetAccountValue(bob) != 70 || loaded.NumUtxos() != 3 {
+		t.Fatalf(`Loaded chain has the wrong utxo set`)
+	}
+	if _, ok := loaded.GetBlockByNumber(1); ok || !loaded.IsBlockPruned(1) {
+		t.Fatalf(`Loaded chain has a block body`)
+	}
+	if _, err := loaded.LoadSnapshot(bytes.NewReader(data)); !errors.Is(err, ErrSnapshotChain) {
+		t.Fatalf(`Expected an error loading a snapshot into a chain with blocks, got %v`, err)
+	}
+
+	// Blocks can be added on top of the snapshot
+	if !loaded.AddBlock(newTestBlock(tip, 2, bob, 5)) || loaded.GetAccountValue(bob) != 75 {
+		t.Fatalf(`Failed to add a block on top of the snapshot`)
+	}
+
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)/2] ^= 1
+	if _, err := untrusted.LoadSnapshot(bytes.NewReader(corrupted)); !errors.Is(err, ErrSnapshotHash) {
+		t.Fatalf(`Expected a hash mismatch error, got %v`, err)
+	}
+}
+
+// Tests that a snapshot taken below the tip matches one taken when that block was the tip
+func TestSnapshotBelowTip(t *testing.T) {
+	chn, _, _, _ := newTestIndexChain(t)
+	genesis, _ := chn.GetBlockByNumber(0)
+	genesisOnly, _ := New(&protocol.DevNetParams)
+	genesisOnly.Initialize(genesis)
+
+	var buf bytes.Buffer
+	below, ok := chn.ExportSnapshot(&buf, 0)
+	if !ok || below.NumUtxos != 1 {
+		t.Fatalf(`Got unexpected snapshot %+v`, below)
+	}
+	atTip, _ := genesisOnly.ExportSnapshot(&bytes.Buffer{}, 0)
+	if !below.Hash.Equal(atTip.Hash) {
+		t.Fatalf(`Snapshot below the tip has hash %v, expected %v`, below.Hash.Hex(), atTip.Hash.Hex())
+	}
+	if chn.NumUtxos() != 3 {
+		t.Fatalf(`Exporting below the tip changed the chain`)
+	}
+	if _, ok := chn.ExportSnapshot(&buf, 2); ok {
+		t.Fatalf(`Exported a snapshot past the tip`)
+	}
+}
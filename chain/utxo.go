@@ -0,0 +1,143 @@
+package chain
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// A utxo entry holds everything needed to validate a spend of an unspent output without loading its transaction
+type UtxoEntry struct {
+	Amount     uint64
+	Owner      common.Address
+	BlockNum   int  // The block number the output was confirmed in
+	IsCoinbase bool // Coinbase outputs can only be spent once they mature
+}
+
+// A utxo set holds the unspent outputs of the chain keyed by outpoint, with a secondary index by owner
+// Lookups, additions and removals take constant time regardless of how many outputs an address owns
+type UtxoSet struct {
+	entries   map[transaction.TransactionOutputPointer]*UtxoEntry
+	byAddress map[common.Address][]*transaction.TransactionOutputPointer // The outpoints owned by each address
+	positions map[transaction.TransactionOutputPointer]int               // The position of each outpoint in its owner's list
+}
+
+// Creates an empty utxo set
+func NewUtxoSet() *UtxoSet {
+	utxoSet := UtxoSet{
+		entries:   make(map[transaction.TransactionOutputPointer]*UtxoEntry),
+		byAddress: make(map[common.Address][]*transaction.TransactionOutputPointer),
+		positions: make(map[transaction.TransactionOutputPointer]int),
+	}
+
+	return &utxoSet
+}
+
+// Adds an unspent output, replacing any existing entry for the same outpoint
+func (us *UtxoSet) Add(ptr transaction.TransactionOutputPointer, entry UtxoEntry) {
+	us.Remove(ptr)
+
+	us.entries[ptr] = &entry
+	us.positions[ptr] = len(us.byAddress[entry.Owner])
+	us.byAddress[entry.Owner] = append(us.byAddress[entry.Owner], &ptr)
+}
+
+// Removes an unspent output, returning its entry
+// Returns a bool indicating success, which fails if the outpoint is not unspent
+func (us *UtxoSet) Remove(ptr transaction.TransactionOutputPointer) (UtxoEntry, bool) {
+	entry, ok := us.entries[ptr]
+	if !ok {
+		return UtxoEntry{}, false
+	}
+
+	// Move the last outpoint of the owner's list into the removed outpoint's position
+	owned := us.byAddress[entry.Owner]
+	position := us.positions[ptr]
+	last := owned[len(owned)-1]
+	owned[position] = last
+	us.positions[*last] = position
+	owned = owned[:len(owned)-1]
+	if len(owned) == 0 {
+		delete(us.byAddress, entry.Owner)
+	} else {
+		us.byAddress[entry.Owner] = owned
+	}
+
+	delete(us.positions, ptr)
+	delete(us.entries, ptr)
+
+	return *entry, true
+}
+
+// Returns the entry of an unspent output
+// Returns a bool indicating success, which fails if the outpoint is not unspent
+func (us *UtxoSet) Get(ptr transaction.TransactionOutputPointer) (UtxoEntry, bool) {
+	entry, ok := us.entries[ptr]
+	if !ok {
+		return UtxoEntry{}, false
+	}
+
+	return *entry, true
+}
+
+// Returns a copy of the outpoints owned by an address
+func (us *UtxoSet) GetByAddress(address common.Address) []*transaction.TransactionOutputPointer {
+	return append([]*transaction.TransactionOutputPointer{}, us.byAddress[address]...)
+}
+
+// Returns the addresses that own at least one unspent output
+func (us *UtxoSet) Addresses() []common.Address {
+	addresses := []common.Address{}
+	for address := range us.byAddress {
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
+
+// Returns every unspent outpoint, sorted by their byte representation
+func (us *UtxoSet) Outpoints() []transaction.TransactionOutputPointer {
+	ptrs := make([]transaction.TransactionOutputPointer, 0, len(us.entries))
+	for ptr := range us.entries {
+		ptrs = append(ptrs, ptr)
+	}
+	sort.Slice(ptrs, func(i, j int) bool {
+		return bytes.Compare(ptrs[i].Bytes(), ptrs[j].Bytes()) < 0
+	})
+
+	return ptrs
+}
+
+// Returns the number of unspent outputs
+func (us *UtxoSet) Len() int {
+	return len(us.entries)
+}
+
+// Returns the total value of all unspent outputs
+func (us *UtxoSet) TotalValue() uint64 {
+	var total uint64 = 0
+	for _, entry := range us.entries {
+		total += entry.Amount
+	}
+
+	return total
+}
+
+// Returns a copy of the set that can be modified without affecting the original
+func (us *UtxoSet) Copy() *UtxoSet {
+	other := NewUtxoSet()
+	for ptr, entry := range us.entries {
+		copied := *entry
+		other.entries[ptr] = &copied
+	}
+	for address, owned := range us.byAddress {
+		other.byAddress[address] = append([]*transaction.TransactionOutputPointer{}, owned...)
+	}
+	for ptr, position := range us.positions {
+		other.positions[ptr] = position
+	}
+
+	return other
+}
@@ -0,0 +1,66 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// Tests that the utxo set keeps its address index in step with its entries
+func TestUtxoSet(t *testing.T) {
+	alice := common.BytesToAddress([]byte{1})
+	bob := common.BytesToAddress([]byte{2})
+	txHash := crypto.HashBytes([]byte("tx"))
+	ptrs := []transaction.TransactionOutputPointer{}
+	for i := 0; i < 4; i++ {
+		ptrs = append(ptrs, transaction.TransactionOutputPointer{TransactionHash: txHash, OutputIndex: uint16(i)})
+	}
+
+	utxos := NewUtxoSet()
+	utxos.Add(ptrs[0], UtxoEntry{Amount: 1, Owner: alice})
+	utxos.Add(ptrs[1], UtxoEntry{Amount: 2, Owner: alice, BlockNum: 3, IsCoinbase: true})
+	utxos.Add(ptrs[2], UtxoEntry{Amount: 4, Owner: alice})
+	utxos.Add(ptrs[3], UtxoEntry{Amount: 8, Owner: bob})
+
+	if entry, ok := utxos.Get(ptrs[1]); !ok || entry.Amount != 2 || entry.BlockNum != 3 || !entry.IsCoinbase {
+		t.Fatalf(`Got unexpected entry %+v`, entry)
+	}
+	if utxos.Len() != 4 || utxos.TotalValue() != 15 {
+		t.Fatalf(`Expected 4 outputs worth 15, got %v worth %v`, utxos.Len(), utxos.TotalValue())
+	}
+
+	copied := utxos.Copy()
+	if _, ok := utxos.Remove(ptrs[0]); !ok {
+		t.Fatalf(`Failed to remove an unspent output`)
+	}
+	if _, ok := utxos.Remove(ptrs[0]); ok {
+		t.Fatalf(`Removed an output twice`)
+	}
+
+	owned := utxos.GetByAddress(alice)
+	if len(owned) != 2 {
+		t.Fatalf(`Expected alice to own 2 outputs, got %v`, len(owned))
+	}
+	for _, ptr := range owned {
+		if ptr.Equal(&ptrs[0]) {
+			t.Fatalf(`Address index still holds a removed output`)
+		}
+	}
+
+	// Removing every output of an address drops it from the index
+	utxos.Remove(ptrs[3])
+	if len(utxos.GetByAddress(bob)) != 0 || len(utxos.Addresses()) != 1 {
+		t.Fatalf(`Address index still holds bob after his outputs were spent`)
+	}
+
+	// The copy is unaffected by changes to the original
+	if copied.Len() != 4 || len(copied.GetByAddress(alice)) != 3 {
+		t.Fatalf(`Copy changed along with the original`)
+	}
+	copied.Remove(ptrs[2])
+	if _, ok := utxos.Get(ptrs[2]); !ok {
+		t.Fatalf(`Original changed along with the copy`)
+	}
+}
@@ -0,0 +1,210 @@
+package chain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// A header check validates the linkage and proof of work of a header, such as Consensus.ValidateBlockHeader
+type HeaderCheck func(previousBlockHash common.Hash, blockNum int, header *block.BlockHeader) error
+
+// Kinds of inconsistency a verification can find
+const (
+	MismatchMissingBlock     = "missing-block"      // A block's header or body is not stored
+	MismatchBlockHash        = "block-hash"         // A block does not hash to the hash it is stored under
+	MismatchHeader           = "header"             // A header fails the header check
+	MismatchTransactionsHash = "transactions-hash"  // A header does not commit to its block's transactions
+	MismatchMissingInput     = "missing-input"      // A transaction spends an output that is not unspent when rebuilding
+	MismatchUtxo             = "utxo"               // The chain's utxo set differs from the rebuilt one
+	MismatchUtxoAddressIndex = "utxo-address-index" // The utxo set's address index disagrees with its entries
+)
+
+// A mismatch describes one inconsistency found when verifying a chain
+type VerifyMismatch struct {
+	BlockNum int // The block the mismatch was found in, or -1 for mismatches in the utxo set
+	Kind     string
+	Detail   string
+}
+
+// A verify report describes what a verification checked and every inconsistency it found
+type VerifyReport struct {
+	TipNum        int // The number of the last block when the chain was verified
+	FromBlock     int // The oldest block whose hashes, transactions and work were checked
+	BlocksChecked int
+	UtxosChecked  bool // False if the utxo set could not be rebuilt because blocks have been pruned
+	NumUtxos      int  // The number of outputs in the rebuilt utxo set
+	Mismatches    []VerifyMismatch
+}
+
+// Returns true if no inconsistencies were found
+func (report *VerifyReport) OK() bool {
+	return len(report.Mismatches) == 0
+}
+
+// Returns a readable report listing every inconsistency found
+func (report *VerifyReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Checked blocks %v to %v (%v blocks)\n", report.FromBlock, report.TipNum, report.BlocksChecked)
+	if report.UtxosChecked {
+		fmt.Fprintf(&sb, "Rebuilt utxo set with %v outputs\n", report.NumUtxos)
+	} else {
+		fmt.Fprintf(&sb, "Did not check utxo set, blocks have been pruned\n")
+	}
+
+	if report.OK() {
+		fmt.Fprintf(&sb, "No inconsistencies found\n")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Found %v inconsistencies\n", len(report.Mismatches))
+	for _, mismatch := range report.Mismatches {
+		if mismatch.BlockNum >= 0 {
+			fmt.Fprintf(&sb, "block %v: %v: %v\n", mismatch.BlockNum, mismatch.Kind, mismatch.Detail)
+		} else {
+			fmt.Fprintf(&sb, "%v: %v\n", mismatch.Kind, mismatch.Detail)
+		}
+	}
+
+	return sb.String()
+}
+
+func (report *VerifyReport) addMismatch(blockNum int, kind string, format string, args ...interface{}) {
+	report.Mismatches = append(report.Mismatches, VerifyMismatch{
+		BlockNum: blockNum,
+		Kind:     kind,
+		Detail:   fmt.Sprintf(format, args...),
+	})
+}
+
+// Re-walks the chain from genesis, checking that it is self-consistent
+// The last depth blocks, or every block if depth is 0, are rehashed, have their transaction hash recomputed,
+// and have their header passed to checkHeader, which is skipped for the genesis block or if checkHeader is nil
+// If no blocks have been pruned, the utxo set is rebuilt from every block and compared to the chain's
+func (chain *Chain) Verify(depth int, checkHeader HeaderCheck) *VerifyReport {
+	chain.mu.RLock()
+	defer chain.mu.RUnlock()
+
+	tipNum := len(chain.blockHashes) - 1
+	report := &VerifyReport{
+		TipNum:       tipNum,
+		FromBlock:    0,
+		UtxosChecked: chain.pruneHeight == 0,
+	}
+	if depth > 0 && depth <= tipNum {
+		report.FromBlock = tipNum + 1 - depth
+	}
+
+	rebuilt := NewUtxoSet()
+	for blockNum := 0; blockNum <= tipNum; blockNum++ {
+		hash := chain.blockHashes[blockNum]
+		header, headerOk := chain.headers[hash]
+		blk, blkOk := chain.blocks[hash]
+		if !headerOk || (blockNum >= chain.pruneHeight && !blkOk) {
+			report.addMismatch(blockNum, MismatchMissingBlock, "no block stored under hash %v", hash.Hex())
+			report.UtxosChecked = false
+			continue
+		}
+
+		if blockNum >= report.FromBlock {
+			chain.verifyBlock(report, blockNum, hash, header, blk, checkHeader)
+		}
+		if report.UtxosChecked {
+			rebuildBlock(report, rebuilt, blockNum, blk)
+		}
+	}
+
+	if report.UtxosChecked {
+		report.NumUtxos = rebuilt.Len()
+		compareUtxos(report, rebuilt, chain.utxos)
+	}
+	checkUtxoAddressIndex(report, chain.utxos)
+
+	return report
+}
+
+// Checks a block's hash, its header and the transactions its header commits to
+func (chain *Chain) verifyBlock(report *VerifyReport, blockNum int, hash common.Hash, header *block.BlockHeader, blk *block.Block, checkHeader HeaderCheck) {
+	report.BlocksChecked++
+	if computed := header.Hash(); !computed.Equal(hash) {
+		report.addMismatch(blockNum, MismatchBlockHash, "header hashes to %v, stored as %v", computed.Hex(), hash.Hex())
+	}
+	if blockNum > 0 && checkHeader != nil {
+		if err := checkHeader(chain.blockHashes[blockNum-1], blockNum, header); err != nil {
+			report.addMismatch(blockNum, MismatchHeader, "%v", err)
+		}
+	}
+
+	// Pruned blocks only have their header left to check
+	if blk == nil {
+		return
+	}
+	if computed := blk.Hash(); !computed.Equal(hash) {
+		report.addMismatch(blockNum, MismatchBlockHash, "block hashes to %v, stored as %v", computed.Hex(), hash.Hex())
+	}
+	computed := block.ComputeAllTransactionsHash(blk.Header.ProtocolVersion, blk.Body, blk.Coinbase)
+	if !computed.Equal(blk.Header.AllTransactionsHash) {
+		report.addMismatch(blockNum, MismatchTransactionsHash, "transactions hash to %v, header has %v",
+			computed.Hex(), blk.Header.AllTransactionsHash.Hex())
+	}
+}
+
+// Applies a block's transactions to a utxo set being rebuilt from genesis
+func rebuildBlock(report *VerifyReport, rebuilt *UtxoSet, blockNum int, blk *block.Block) {
+	txs := append(append([]*transaction.Transaction{}, blk.Body...), blk.Coinbase)
+	for _, tx := range txs {
+		txHash := tx.Hash()
+		for _, input := range tx.Inputs {
+			if _, ok := rebuilt.Remove(*input.OutputPointer); !ok {
+				report.addMismatch(blockNum, MismatchMissingInput, "transaction %v spends output %v of %v, which is not unspent",
+					txHash.Hex(), input.OutputPointer.OutputIndex, input.OutputPointer.TransactionHash.Hex())
+			}
+		}
+		for outputIndex, output := range tx.Outputs {
+			rebuilt.Add(transaction.TransactionOutputPointer{TransactionHash: txHash, OutputIndex: uint16(outputIndex)}, UtxoEntry{
+				Amount:     output.Amount,
+				Owner:      output.ReceiverAddress,
+				BlockNum:   blockNum,
+				IsCoinbase: len(tx.Inputs) == 0,
+			})
+		}
+	}
+}
+
+// Reports every output that is missing from, extra in, or different in the chain's utxo set compared to the rebuilt one
+func compareUtxos(report *VerifyReport, rebuilt *UtxoSet, utxos *UtxoSet) {
+	for _, ptr := range rebuilt.Outpoints() {
+		expected, _ := rebuilt.Get(ptr)
+		actual, ok := utxos.Get(ptr)
+		if !ok {
+			report.addMismatch(-1, MismatchUtxo, "output %v of %v is missing", ptr.OutputIndex, ptr.TransactionHash.Hex())
+		} else if actual != expected {
+			report.addMismatch(-1, MismatchUtxo, "output %v of %v is %+v, expected %+v", ptr.OutputIndex, ptr.TransactionHash.Hex(), actual, expected)
+		}
+	}
+	for _, ptr := range utxos.Outpoints() {
+		if _, ok := rebuilt.Get(ptr); !ok {
+			report.addMismatch(-1, MismatchUtxo, "output %v of %v is not created by any block", ptr.OutputIndex, ptr.TransactionHash.Hex())
+		}
+	}
+}
+
+// Reports any outpoint the utxo set's address index lists under the wrong owner, or any entry it does not list
+func checkUtxoAddressIndex(report *VerifyReport, utxos *UtxoSet) {
+	indexed := 0
+	for address, owned := range utxos.byAddress {
+		for _, ptr := range owned {
+			indexed++
+			entry, ok := utxos.entries[*ptr]
+			if !ok || !entry.Owner.Equal(address) {
+				report.addMismatch(-1, MismatchUtxoAddressIndex, "output %v of %v is indexed under %v but not owned by it",
+					ptr.OutputIndex, ptr.TransactionHash.Hex(), address.Hex())
+			}
+		}
+	}
+	if indexed != utxos.Len() {
+		report.addMismatch(-1, MismatchUtxoAddressIndex, "address index lists %v outputs, set has %v", indexed, utxos.Len())
+	}
+}
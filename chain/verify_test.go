@@ -0,0 +1,91 @@
+package chain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// Tests that a consistent chain verifies and that tampered blocks and utxos are reported
+func TestVerify(t *testing.T) {
+	chn, alice, _, payment := newTestIndexChain(t)
+	tip, _, _ := chn.GetLastBlockInfo()
+	chn.AddBlock(newTestBlock(tip, 2, alice.Address, 5))
+
+	checked := []int{}
+	checkHeader := func(prevHash common.Hash, blockNum int, header *block.BlockHeader) error {
+		checked = append(checked, blockNum)
+		return nil
+	}
+	report := chn.Verify(0, checkHeader)
+	if !report.OK() || report.BlocksChecked != 3 || !report.UtxosChecked || report.NumUtxos != 4 || len(checked) != 2 {
+		t.Fatalf(`Got unexpected report for a consistent chain: %v`, report)
+	}
+
+	// Tampering with a confirmed transaction breaks its block's transactions hash and the utxo set
+	original := payment.Outputs[0].Amount
+	payment.Outputs[0].Amount = original + 1
+	report = chn.Verify(0, nil)
+	if !hasMismatch(report, 1, MismatchTransactionsHash) || !hasMismatch(report, -1, MismatchUtxo) {
+		t.Fatalf(`Tampered transaction was not reported: %v`, report)
+	}
+	payment.Outputs[0].Amount = original
+
+	// Only the last depth blocks have their hashes checked, but the utxo set is always rebuilt
+	genesis, _ := chn.GetBlockByNumber(0)
+	genesis.Header.Nonce++
+	if report := chn.Verify(1, nil); report.FromBlock != 2 || report.BlocksChecked != 1 || !report.OK() {
+		t.Fatalf(`Checked blocks below the depth: %v`, report)
+	}
+	if report := chn.Verify(0, nil); !hasMismatch(report, 0, MismatchBlockHash) {
+		t.Fatalf(`Tampered header was not reported: %v`, report)
+	}
+	genesis.Header.Nonce--
+
+	// A failing header check is reported against its block
+	report = chn.Verify(0, func(prevHash common.Hash, blockNum int, header *block.BlockHeader) error {
+		if blockNum == 2 {
+			return errors.New("insufficient work")
+		}
+		return nil
+	})
+	if !hasMismatch(report, 2, MismatchHeader) || !strings.Contains(report.String(), "insufficient work") {
+		t.Fatalf(`Failed header check was not reported: %v`, report)
+	}
+
+	// Outputs missing from or added to the utxo set are reported
+	coinbasePtr := transaction.TransactionOutputPointer{TransactionHash: genesis.Coinbase.Hash()}
+	chn.utxos.Add(coinbasePtr, UtxoEntry{Amount: 100, Owner: alice.Address})
+	paymentPtr := transaction.TransactionOutputPointer{TransactionHash: payment.Hash()}
+	chn.utxos.Remove(paymentPtr)
+	report = chn.Verify(0, nil)
+	if len(report.Mismatches) != 2 || !hasMismatch(report, -1, MismatchUtxo) {
+		t.Fatalf(`Expected a missing and an extra output to be reported: %v`, report)
+	}
+}
+
+// Tests that a pruned chain checks what it still has and skips rebuilding the utxo set
+func TestVerifyPruned(t *testing.T) {
+	chn := newTestPruneChain(common.BytesToAddress([]byte{1}), 20)
+	chn.EnablePruning(PruneConfig{Depth: 10})
+
+	report := chn.Verify(0, nil)
+	if !report.OK() || report.UtxosChecked || report.BlocksChecked != 20 {
+		t.Fatalf(`Got unexpected report for a pruned chain: %v`, report)
+	}
+}
+
+// Returns true if the report has a mismatch of the given kind at the given block number
+func hasMismatch(report *VerifyReport, blockNum int, kind string) bool {
+	for _, mismatch := range report.Mismatches {
+		if mismatch.BlockNum == blockNum && mismatch.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+package chain
+
+import (
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// A state is the ledger as seen by transaction validation, either the chain itself or a view layered over it
+type State interface {
+	Params() *protocol.Params
+	GetLastBlockInfo() (hash common.Hash, blockNum int, ok bool)
+	GetUtxo(ptr *transaction.TransactionOutputPointer) (entry UtxoEntry, ok bool)
+	IsOutputMature(ptr *transaction.TransactionOutputPointer) bool
+	GetPendingTransactionFee(tx *transaction.Transaction) (fee uint64, ok bool)
+}
+
+var (
+	_ State = (*Chain)(nil)
+	_ State = (*View)(nil)
+)
+
+// A view layers the effects of transactions in the next block over the chain without copying it
+// Changes stay in the view and never reach the chain, which only changes when a block is added
+type View struct {
+	base         *Chain
+	baseTip      common.Hash // The tip of the chain when the view was created
+	blockNum     int         // The number of the block the view's transactions would be confirmed in
+	added        map[transaction.TransactionOutputPointer]UtxoEntry
+	spent        map[transaction.TransactionOutputPointer]bool
+	transactions []*transaction.Transaction // Transactions applied to the view, in order
+}
+
+// Creates an empty view over the current tip of the chain
+func (chain *Chain) NewView() *View {
+	tip, lastBlockNum, _ := chain.GetLastBlockInfo()
+	view := View{
+		base:     chain,
+		baseTip:  tip,
+		blockNum: lastBlockNum + 1,
+		added:    make(map[transaction.TransactionOutputPointer]UtxoEntry),
+		spent:    make(map[transaction.TransactionOutputPointer]bool),
+	}
+
+	return &view
+}
+
+func (view *View) Params() *protocol.Params {
+	return view.base.Params()
+}
+
+func (view *View) GetLastBlockInfo() (hash common.Hash, blockNum int, ok bool) {
+	return view.baseTip, view.blockNum - 1, true
+}
+
+// Gets the entry of an output that is unspent in the view
+// Returns bool indicating success, which fails if the output does not exist or has been spent in the view or the chain
+func (view *View) GetUtxo(ptr *transaction.TransactionOutputPointer) (entry UtxoEntry, ok bool) {
+	if view.spent[*ptr] {
+		return UtxoEntry{}, false
+	}
+	if entry, ok := view.added[*ptr]; ok {
+		return entry, true
+	}
+
+	return view.base.GetUtxo(ptr)
+}
+
+// Returns true if an output can be spent in the block the view is building
+func (view *View) IsOutputMature(ptr *transaction.TransactionOutputPointer) bool {
+	entry, ok := view.GetUtxo(ptr)
+	if !ok || !entry.IsCoinbase {
+		return true
+	}
+
+	return view.Params().IsCoinbaseMature(entry.BlockNum, view.blockNum)
+}
+
+// Given a transaction spending outputs unspent in the view, return its transaction fee
+// Returns bool indicating success, which fails if an input is unknown, a total overflows, or outputs exceed inputs
+func (view *View) GetPendingTransactionFee(tx *transaction.Transaction) (fee uint64, ok bool) {
+	return computeFee(tx, func(ptr *transaction.TransactionOutputPointer) (uint64, bool) {
+		if entry, ok := view.GetUtxo(ptr); ok {
+			return entry.Amount, true
+		}
+
+		return view.base.GetOutputAmount(ptr)
+	})
+}
+
+// Applies a transaction to the view, spending its inputs and creating its outputs
+// Returns bool indicating success
+// This is not a smart function - the transaction should be validated against the view first
+func (view *View) AddTransaction(tx *transaction.Transaction) bool {
+	for _, input := range tx.Inputs {
+		ptr := *input.OutputPointer
+		if _, ok := view.added[ptr]; ok {
+			delete(view.added, ptr)
+		} else {
+			view.spent[ptr] = true
+		}
+	}
+
+	txHash := tx.Hash()
+	isCoinbase := len(tx.Inputs) == 0
+	for outputIndex, output := range tx.Outputs {
+		outputPointer := transaction.TransactionOutputPointer{
+			TransactionHash: txHash,
+			OutputIndex:     uint16(outputIndex),
+		}
+		view.added[outputPointer] = UtxoEntry{
+			Amount:     output.Amount,
+			Owner:      output.ReceiverAddress,
+			BlockNum:   view.blockNum,
+			IsCoinbase: isCoinbase,
+		}
+	}
+
+	view.transactions = append(view.transactions, tx)
+	return true
+}
+
+// Returns the transactions applied to the view, in order
+func (view *View) Transactions() []*transaction.Transaction {
+	return append([]*transaction.Transaction{}, view.transactions...)
+}
+
+// Drops the view's changes, leaving it empty over the same tip
+func (view *View) Discard() {
+	view.added = make(map[transaction.TransactionOutputPointer]UtxoEntry)
+	view.spent = make(map[transaction.TransactionOutputPointer]bool)
+	view.transactions = nil
+}
@@ -0,0 +1,71 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/account"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// Creates a signed transaction spending the output at ptr, owned by act, into a single output to act
+func newTestSpend(act *account.Account, ptr *transaction.TransactionOutputPointer, amount uint64) *transaction.Transaction {
+	signature, _ := crypto.SignByteArray(ptr.Bytes(), act.PrivateKey)
+	verification := &transaction.TransactionInputVerification{
+		Signature:        signature,
+		EncodedPublicKey: act.PublicKey,
+	}
+	input := &transaction.TransactionInput{
+		OutputPointer:      ptr,
+		VerificationLength: uint16(len(verification.Bytes())),
+		Verification:       verification,
+	}
+	output := &transaction.TransactionOutput{ReceiverAddress: act.Address, Amount: amount}
+	tx, _ := transaction.New([]*transaction.TransactionInput{input}, []*transaction.TransactionOutput{output})
+
+	return tx
+}
+
+// Tests that a view layers spends over the chain without changing it
+func TestView(t *testing.T) {
+	act, _ := account.New()
+	chn, _ := New(&protocol.DevNetParams)
+	genesis := newTestBlock(common.Hash{}, 0, act.Address, 100)
+	chn.Initialize(genesis)
+	genesisPtr := &transaction.TransactionOutputPointer{TransactionHash: genesis.Coinbase.Hash()}
+
+	view := chn.NewView()
+	first := newTestSpend(act, genesisPtr, 90)
+	view.AddTransaction(first)
+	firstPtr := &transaction.TransactionOutputPointer{TransactionHash: first.Hash()}
+
+	// A later transaction can spend an output created earlier in the view
+	second := newTestSpend(act, firstPtr, 80)
+	if fee, ok := view.GetPendingTransactionFee(second); !ok || fee != 10 {
+		t.Fatalf(`Expected a fee of 10 spending an output created in the view, got %v`, fee)
+	}
+	view.AddTransaction(second)
+	secondPtr := &transaction.TransactionOutputPointer{TransactionHash: second.Hash()}
+
+	if _, ok := view.GetUtxo(genesisPtr); ok {
+		t.Fatalf(`Output spent in the view is still unspent in the view`)
+	}
+	if _, ok := view.GetUtxo(firstPtr); ok {
+		t.Fatalf(`Output created and spent in the view is still unspent in the view`)
+	}
+	if entry, ok := view.GetUtxo(secondPtr); !ok || entry.Amount != 80 || entry.BlockNum != 1 {
+		t.Fatalf(`Output created in the view is missing or wrong: %+v`, entry)
+	}
+
+	// The chain is untouched by the view
+	if _, ok := chn.GetUtxo(genesisPtr); !ok || chn.GetAccountValue(act.Address) != 100 {
+		t.Fatalf(`View changed the chain`)
+	}
+
+	view.Discard()
+	if _, ok := view.GetUtxo(genesisPtr); !ok || len(view.Transactions()) != 0 {
+		t.Fatalf(`Discarded view still holds its changes`)
+	}
+}
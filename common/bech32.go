@@ -0,0 +1,170 @@
+package common
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	bech32Charset        = "qpzry9x8gf2tvdw0s3jn54khce6mua7l" // The 32 characters used to encode 5 bit groups
+	bech32Separator      = '1'                                // Separates the human readable prefix from the data
+	bech32ChecksumLength = 6                                  // The number of characters in the checksum
+	bech32MaxLength      = 90                                 // The maximum length of an encoded string
+)
+
+var (
+	ErrBech32Length    = errors.New("bech32 string has an invalid length")
+	ErrBech32Case      = errors.New("bech32 string mixes upper and lower case")
+	ErrBech32Character = errors.New("bech32 string contains an invalid character")
+	ErrBech32Separator = errors.New("bech32 string is missing its separator")
+	ErrBech32Checksum  = errors.New("bech32 checksum does not match")
+	ErrBech32Padding   = errors.New("bech32 data has invalid padding")
+)
+
+// Encodes data with a human readable prefix into a bech32 string with an error detecting checksum
+func Bech32Encode(prefix string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	prefix = strings.ToLower(prefix)
+	if len(prefix) == 0 || len(prefix)+1+len(values)+bech32ChecksumLength > bech32MaxLength {
+		return "", ErrBech32Length
+	}
+
+	checksum := bech32Checksum(prefix, values)
+
+	var builder strings.Builder
+	builder.WriteString(prefix)
+	builder.WriteByte(bech32Separator)
+	for _, value := range append(values, checksum...) {
+		builder.WriteByte(bech32Charset[value])
+	}
+
+	return builder.String(), nil
+}
+
+// Decodes a bech32 string into its human readable prefix and data, verifying its checksum
+func Bech32Decode(encoded string) (prefix string, data []byte, err error) {
+	if len(encoded) > bech32MaxLength {
+		return "", nil, ErrBech32Length
+	}
+
+	lower := strings.ToLower(encoded)
+	if lower != encoded && strings.ToUpper(encoded) != encoded {
+		return "", nil, ErrBech32Case
+	}
+
+	separatorIndex := strings.LastIndexByte(lower, bech32Separator)
+	if separatorIndex < 1 {
+		return "", nil, ErrBech32Separator
+	}
+	if separatorIndex+1+bech32ChecksumLength > len(lower) {
+		return "", nil, ErrBech32Length
+	}
+
+	prefix = lower[:separatorIndex]
+	for _, c := range []byte(prefix) {
+		if c < 33 || c > 126 {
+			return "", nil, ErrBech32Character
+		}
+	}
+
+	values := make([]byte, 0, len(lower)-separatorIndex-1)
+	for _, c := range []byte(lower[separatorIndex+1:]) {
+		value := strings.IndexByte(bech32Charset, c)
+		if value == -1 {
+			return "", nil, ErrBech32Character
+		}
+		values = append(values, byte(value))
+	}
+
+	if bech32Polymod(append(bech32ExpandPrefix(prefix), values...)) != 1 {
+		return "", nil, ErrBech32Checksum
+	}
+
+	data, err = convertBits(values[:len(values)-bech32ChecksumLength], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return prefix, data, nil
+}
+
+// Computes the bech32 checksum generator polynomial over a list of 5 bit values
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+
+	return checksum
+}
+
+// Expands a human readable prefix into the 5 bit values included in the checksum
+func bech32ExpandPrefix(prefix string) []byte {
+	expanded := make([]byte, 0, len(prefix)*2+1)
+	for _, c := range []byte(prefix) {
+		expanded = append(expanded, c>>5)
+	}
+	expanded = append(expanded, 0)
+	for _, c := range []byte(prefix) {
+		expanded = append(expanded, c&31)
+	}
+
+	return expanded
+}
+
+// Computes the 6 checksum values for a prefix and its data
+func bech32Checksum(prefix string, values []byte) []byte {
+	checksumInput := append(bech32ExpandPrefix(prefix), values...)
+	checksumInput = append(checksumInput, make([]byte, bech32ChecksumLength)...)
+	polymod := bech32Polymod(checksumInput) ^ 1
+
+	checksum := make([]byte, bech32ChecksumLength)
+	for i := range checksum {
+		checksum[i] = byte((polymod >> uint(5*(5-i))) & 31)
+	}
+
+	return checksum
+}
+
+// Regroups a list of fromBits sized values into toBits sized values
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	var accumulator uint32
+	var bits uint
+	maxValue := uint32(1)<<toBits - 1
+
+	converted := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, value := range data {
+		if uint32(value)>>fromBits != 0 {
+			return nil, ErrBech32Character
+		}
+
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(accumulator>>bits&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(accumulator<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || accumulator<<(toBits-bits)&maxValue != 0 {
+		return nil, ErrBech32Padding
+	}
+
+	return converted, nil
+}
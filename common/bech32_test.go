@@ -0,0 +1,88 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests that valid bech32 strings from the BIP-173 specification decode
+func TestBech32ValidStrings(t *testing.T) {
+	validStrings := []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	}
+
+	for _, encoded := range validStrings {
+		prefix, data, err := Bech32Decode(encoded)
+		if err != nil {
+			t.Fatalf(`Failed to decode valid bech32 string %v: %v`, encoded, err)
+		}
+
+		reencoded, err := Bech32Encode(prefix, data)
+		if err != nil || reencoded != strings.ToLower(encoded) {
+			t.Fatalf(`Re-encoded string does not match. Original: %v, Re-encoded: %v`, encoded, reencoded)
+		}
+	}
+}
+
+// Tests that corrupted bech32 strings are rejected
+func TestBech32InvalidStrings(t *testing.T) {
+	invalidStrings := []string{
+		"pzry9x0s0muk",  // No separator
+		"1pzry9x0s0muk", // Empty prefix
+		"x1b4n0q5v",     // Invalid data character
+		"li1dgmt3",      // Checksum too short
+		"A1G7SGD8",      // Checksum calculated with upper case prefix
+		"a12UEL5L",      // Mixed case
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxx", // Bad checksum
+	}
+
+	for _, encoded := range invalidStrings {
+		if _, _, err := Bech32Decode(encoded); err == nil {
+			t.Fatalf(`Decoded invalid bech32 string %v`, encoded)
+		}
+	}
+}
+
+// Tests that addresses survive encoding and that typos or other networks are rejected
+func TestEncodeDecodeAddress(t *testing.T) {
+	address := BytesToAddress([]byte("a testcoin address of 32 bytes!!"))
+
+	encoded := address.Encode("tc")
+	if !strings.HasPrefix(encoded, "tc1") {
+		t.Fatalf(`Encoded address does not start with its network prefix: %v`, encoded)
+	}
+
+	decoded, err := DecodeAddress(encoded, "tc")
+	if err != nil || !decoded.Equal(address) {
+		t.Fatalf(`Decoded address does not match. Original: %v, Decoded: %v`, address.Hex(), decoded.Hex())
+	}
+
+	decoded, err = DecodeAddress(strings.ToUpper(encoded), "tc")
+	if err != nil || !decoded.Equal(address) {
+		t.Fatalf(`Failed to decode upper case address`)
+	}
+
+	if _, err := DecodeAddress(encoded, "tct"); err != ErrAddressNetwork {
+		t.Fatalf(`Decoded address for the wrong network`)
+	}
+
+	// Change one character of the data
+	typo := []byte(encoded)
+	if typo[10] == 'q' {
+		typo[10] = 'p'
+	} else {
+		typo[10] = 'q'
+	}
+	if _, err := DecodeAddress(string(typo), "tc"); err != ErrBech32Checksum {
+		t.Fatalf(`Decoded address containing a typo`)
+	}
+
+	short, _ := Bech32Encode("tc", address.Bytes()[:20])
+	if _, err := DecodeAddress(short, "tc"); err != ErrAddressLength {
+		t.Fatalf(`Decoded address with the wrong length`)
+	}
+}
@@ -2,7 +2,9 @@ package common
 
 import (
 	"encoding/hex"
+	"errors"
 	"reflect"
+	"strings"
 )
 
 const (
@@ -11,6 +13,11 @@ const (
 	TargetLength  = 4  // A target represents the first 4 bytes of the hash a block must compare itself to
 )
 
+var (
+	ErrAddressNetwork = errors.New("address belongs to a different network")
+	ErrAddressLength  = errors.New("address has an invalid length")
+)
+
 // A hash is a 32 byte SHA256 hash of data.
 type Hash [HashLength]byte
 
@@ -75,6 +82,34 @@ func (a1 Address) Equal(a2 Address) bool {
 	return reflect.DeepEqual(a1, a2)
 }
 
+// Encodes an address into a human readable string with a network prefix and an error detecting checksum
+func (a Address) Encode(prefix string) string {
+	encoded, err := Bech32Encode(prefix, a.Bytes())
+	if err != nil {
+		return ""
+	}
+
+	return encoded
+}
+
+// Decodes a human readable address, rejecting addresses that are corrupted or belong to another network
+func DecodeAddress(encoded string, prefix string) (Address, error) {
+	decodedPrefix, data, err := Bech32Decode(encoded)
+	if err != nil {
+		return Address{}, err
+	}
+
+	if decodedPrefix != strings.ToLower(prefix) {
+		return Address{}, ErrAddressNetwork
+	}
+
+	if len(data) != AddressLength {
+		return Address{}, ErrAddressLength
+	}
+
+	return BytesToAddress(data), nil
+}
+
 // A target is the first 4 bytes of the hash a block must compare itself to while mining.
 type Target [TargetLength]byte
 
@@ -3,17 +3,23 @@ package consensus
 import (
 	"github.com/AndrewCLu/TestcoinNode/block"
 	"github.com/AndrewCLu/TestcoinNode/chain"
+	"github.com/AndrewCLu/TestcoinNode/common"
 	"github.com/AndrewCLu/TestcoinNode/crypto"
 	"github.com/AndrewCLu/TestcoinNode/transaction"
 )
 
 // A consensus provides agreed upon methods for coordinating a shared state of the blockchain
 type Consensus interface {
-	// Returns a boolean indicating if the given transaction is valid based on the current state of the blockchain
-	ValidatePendingTransaction(chain *chain.Chain, tx *transaction.Transaction) bool
+	// Returns nil if the given transaction is valid based on the given state of the ledger, or an error explaining why it is not
+	// The state may be the chain itself or a view holding the earlier transactions of a block being built or validated
+	ValidatePendingTransaction(state chain.State, tx *transaction.Transaction) error
 
-	// Returns a boolean indicating if the given block is valid based on the current state of the blockchain
-	ValidateBlock(chain *chain.Chain, block *block.Block) bool
+	// Returns nil if the given block is valid based on the current state of the blockchain, or an error explaining why it is not
+	ValidateBlock(chain *chain.Chain, block *block.Block) error
+
+	// Returns nil if the given header links to the previous block hash and is valid for the given block number,
+	// or an error explaining why it is not
+	ValidateBlockHeader(previousBlockHash common.Hash, blockNum int, header *block.BlockHeader) error
 
 	// Given a private key and a transaction output pointer, returns a valid signature for the given output
 	SignInput(privateKey []byte, outputPointer *transaction.TransactionOutputPointer) *crypto.ECDSASignature
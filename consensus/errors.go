@@ -0,0 +1,83 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+)
+
+// Reasons a block header is rejected
+var (
+	ErrMissingPrevBlock = errors.New("missing-prev-block")
+	ErrBadVersion       = errors.New("bad-version")
+	ErrBadPrevHash      = errors.New("bad-prev-hash")
+	ErrBadTarget        = errors.New("bad-target")
+	ErrInsufficientWork = errors.New("insufficient-work")
+)
+
+// Reasons a block is rejected
+var (
+	ErrBlockTooLarge    = errors.New("block-too-large")
+	ErrBadMerkleRoot    = errors.New("bad-merkle-root")
+	ErrFeesOverflow     = errors.New("fees-overflow")
+	ErrCoinbaseInputs   = errors.New("coinbase-has-inputs")
+	ErrCoinbaseOutputs  = errors.New("coinbase-output-count")
+	ErrCoinbaseTooLarge = errors.New("coinbase-too-large")
+)
+
+// Reasons a transaction is rejected
+var (
+	ErrDoubleSpend         = errors.New("double-spend")
+	ErrBadSignature        = errors.New("bad-signature")
+	ErrMissingInput        = errors.New("missing-or-spent-input")
+	ErrImmatureCoinbase    = errors.New("immature-coinbase-spend")
+	ErrInputsOverflow      = errors.New("inputs-overflow")
+	ErrZeroOutput          = errors.New("zero-output")
+	ErrOutputTooLarge      = errors.New("output-too-large")
+	ErrOutputsOverflow     = errors.New("outputs-overflow")
+	ErrOutputsExceedInputs = errors.New("outputs-exceed-inputs")
+)
+
+// An input error records which input of a transaction was rejected
+type InputError struct {
+	Index int
+	Err   error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("input %v: %v", e.Index, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// An output error records which output of a transaction was rejected
+type OutputError struct {
+	Index int
+	Err   error
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("output %v: %v", e.Index, e.Err)
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
+
+// A transaction error records which transaction of a block was rejected
+type TransactionError struct {
+	Index int // The position of the transaction in the block body
+	Hash  common.Hash
+	Err   error
+}
+
+func (e *TransactionError) Error() string {
+	return fmt.Sprintf("transaction %v (%v): %v", e.Index, e.Hash.Hex(), e.Err)
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
@@ -2,18 +2,25 @@ package pow
 
 import (
 	"bytes"
-	"fmt"
+	"time"
 
 	"github.com/AndrewCLu/TestcoinNode/account"
 	"github.com/AndrewCLu/TestcoinNode/block"
 	"github.com/AndrewCLu/TestcoinNode/chain"
 	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/consensus"
 	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/logging"
+	"github.com/AndrewCLu/TestcoinNode/metrics"
 	"github.com/AndrewCLu/TestcoinNode/protocol"
 	"github.com/AndrewCLu/TestcoinNode/transaction"
 	"github.com/AndrewCLu/TestcoinNode/util"
 )
 
+var log = logging.Get("consensus")
+
+var validateBlockSeconds = metrics.NewHistogram("testcoin_consensus_validate_block_seconds", "Time taken to validate a block.", metrics.DefaultLatencyBuckets)
+
 // Pow is a consensus mechanism based on proof-of-work
 type Pow struct {
 }
@@ -24,11 +31,12 @@ func New() (p *Pow, ok bool) {
 	return &pow, true
 }
 
-// Returns if a transaction is valid or not based on the state of the ledger
-func (pow *Pow) ValidatePendingTransaction(chain *chain.Chain, tx *transaction.Transaction) bool {
+// Returns nil if a transaction is valid based on the state of the ledger
+// Otherwise returns an error wrapping the reason, with the index of the offending input or output
+func (pow *Pow) ValidatePendingTransaction(state chain.State, tx *transaction.Transaction) error {
 	var inputTotal uint64 = 0
 	usedUtxoHashes := []common.Hash{}
-	for _, input := range tx.Inputs {
+	for i, input := range tx.Inputs {
 		ptr := input.OutputPointer
 		verification := input.Verification
 		signature := verification.Signature
@@ -39,130 +47,181 @@ func (pow *Pow) ValidatePendingTransaction(chain *chain.Chain, tx *transaction.T
 		utxoHash := ptr.Hash()
 		for _, usedHash := range usedUtxoHashes {
 			if utxoHash.Equal(usedHash) {
-				return false
+				return &consensus.InputError{Index: i, Err: consensus.ErrDoubleSpend}
 			}
 		}
 		usedUtxoHashes = append(usedUtxoHashes, utxoHash)
 
 		// Verify that the input is actually signed by the utxo possessor
 		if !pow.VerifyInput(senderPublicKey, ptr, signature) {
-			return false
+			return &consensus.InputError{Index: i, Err: consensus.ErrBadSignature}
 		}
 
-		// Gets a list of valid utxos for the sender
-		// Because we verified the input using the sender key,
-		// any utxo in this list belongs to the account that signed the current input
-		outputPointers, _ := chain.GetUnspentTransactions(senderAddress)
-
-		// Find if a current utxo matches the one implied by the transaction
-		match := false
-		for _, comparePtr := range outputPointers {
-			if ptr.Equal(comparePtr) {
-				match = true
-			}
+		// The output must be unspent and belong to the account that signed the input
+		utxo, utxoOk := state.GetUtxo(ptr)
+		if !utxoOk || !utxo.Owner.Equal(senderAddress) {
+			return &consensus.InputError{Index: i, Err: consensus.ErrMissingInput}
 		}
 
-		if !match {
-			return false
+		// Coinbase outputs cannot be spent until they have enough confirmations
+		if !state.IsOutputMature(ptr) {
+			return &consensus.InputError{Index: i, Err: consensus.ErrImmatureCoinbase}
 		}
 
-		amount, _ := chain.GetOutputAmount(ptr)
-		inputTotal += amount
+		total, ok := util.Amount(inputTotal).Add(util.Amount(utxo.Amount))
+		if !ok {
+			return &consensus.InputError{Index: i, Err: consensus.ErrInputsOverflow}
+		}
+		inputTotal = uint64(total)
 	}
 
 	var outputTotal uint64 = 0
-	for _, output := range tx.Outputs {
-		outputTotal += output.Amount
+	for i, output := range tx.Outputs {
+		if output.Amount == 0 {
+			return &consensus.OutputError{Index: i, Err: consensus.ErrZeroOutput}
+		}
+		if output.Amount > protocol.MaxOutputValue {
+			return &consensus.OutputError{Index: i, Err: consensus.ErrOutputTooLarge}
+		}
+
+		total, ok := util.Amount(outputTotal).Add(util.Amount(output.Amount))
+		if !ok {
+			return &consensus.OutputError{Index: i, Err: consensus.ErrOutputsOverflow}
+		}
+		outputTotal = uint64(total)
 	}
 
-	return inputTotal >= outputTotal
+	if outputTotal > inputTotal {
+		return consensus.ErrOutputsExceedInputs
+	}
+
+	return nil
 }
 
-// Returns boolean indicating if a coinbase transaction is valid or not based onn the state of the ledger
-func (pow *Pow) ValidateCoinbaseTransaction(chain *chain.Chain, coinbase *transaction.Transaction) bool {
+// Returns nil if a coinbase transaction is valid based on the state of the ledger
+// The coinbase may collect at most the block reward plus the fees of the transactions in its block
+func (pow *Pow) ValidateCoinbaseTransaction(state chain.State, coinbase *transaction.Transaction, transactionFees uint64) error {
 	if len(coinbase.Inputs) > 0 {
-		fmt.Println("Coinbase transaction cannot have any inputs")
-		return false
+		return consensus.ErrCoinbaseInputs
 	}
 
 	if len(coinbase.Outputs) != 1 {
-		fmt.Println("Coinbase transaction can only have one output")
+		return consensus.ErrCoinbaseOutputs
+	}
+	if coinbase.Outputs[0].Amount > protocol.MaxOutputValue {
+		return &consensus.OutputError{Index: 0, Err: consensus.ErrOutputTooLarge}
 	}
 
-	_, lastBlockNum, lastBlockOk := chain.GetLastBlockInfo()
+	_, lastBlockNum, lastBlockOk := state.GetLastBlockInfo()
 	if !lastBlockOk {
-		fmt.Println("Could not get last block from current chain")
-		return false
+		return consensus.ErrMissingPrevBlock
 	}
 	blockNum := lastBlockNum + 1
-	blockReward := protocol.ComputeBlockReward(blockNum)
-	if coinbase.Outputs[0].Amount != blockReward {
-		fmt.Println("Incorrect block reward for given block number")
-		return false
+	blockReward := state.Params().ComputeBlockReward(blockNum)
+	maxCoinbase, ok := util.Amount(blockReward).Add(util.Amount(transactionFees))
+	if !ok {
+		return consensus.ErrFeesOverflow
+	}
+	if coinbase.Outputs[0].Amount > uint64(maxCoinbase) {
+		return consensus.ErrCoinbaseTooLarge
+	}
+
+	return nil
+}
+
+// Returns nil if a block is valid given the state of the ledger
+// Otherwise returns an error wrapping the reason, with the offending transaction if there is one
+func (pow *Pow) ValidateBlock(chn *chain.Chain, blk *block.Block) error {
+	start := time.Now()
+	err := pow.validateBlock(chn, blk)
+	validateBlockSeconds.ObserveSince(start)
+	if err != nil {
+		log.Debug("Block failed validation", "hash", blk.Hash().Hex(), "err", err)
 	}
 
-	return true
+	return err
 }
 
-// Returns if a block is valid or not given the state of the ledger
-// TODO: Make sure input validation is done dynamically - each transaction should update the utxo state
-func (pow *Pow) ValidateBlock(chn *chain.Chain, block *block.Block) bool {
-	header := block.Header
-	transactions := block.Body
+func (pow *Pow) validateBlock(chn *chain.Chain, blk *block.Block) error {
+	header := blk.Header
+	transactions := blk.Body
 	prevHash, prevBlockNum, success := chn.GetLastBlockInfo()
 	// Previous block is retrievable
 	if !success {
-		return false
+		return consensus.ErrMissingPrevBlock
 	}
 
-	// Number of transactions does not exceed limit
-	if len(transactions) > protocol.MaxTransactionsInBlock {
-		return false
+	// Header links to the last block and solves proof of work
+	if err := pow.ValidateBlockHeader(prevHash, prevBlockNum+1, header); err != nil {
+		return err
 	}
 
-	// PreviousBlockHash corresponds to last block
-	if bytes.Compare(prevHash[:], header.PreviousBlockHash[:]) != 0 {
-		return false
+	// Size of the block does not exceed limit
+	if blk.Size() > protocol.MaxBlockSize {
+		return consensus.ErrBlockTooLarge
 	}
 
-	// Validate coinbase transaction
-	coinbase := block.Coinbase
-	if !pow.ValidateCoinbaseTransaction(chn, coinbase) {
-		return false
-	}
-
-	// Creates a new chain to test validity of this block
-	tempChain := chn.UnsafeCopy()
-	transactionHashes := make([][]byte, len(transactions)+1)
+	// Applies each transaction to a view so later transactions can spend earlier ones without touching the chain
+	view := chn.NewView()
+	var transactionFees uint64 = 0
 	for i, tx := range transactions {
-		if !pow.ValidatePendingTransaction(tempChain, tx) {
-			return false
+		if err := pow.ValidatePendingTransaction(view, tx); err != nil {
+			return &consensus.TransactionError{Index: i, Hash: tx.Hash(), Err: err}
+		}
+		fee, _ := view.GetPendingTransactionFee(tx)
+		fees, ok := util.Amount(transactionFees).Add(util.Amount(fee))
+		if !ok {
+			return consensus.ErrFeesOverflow
 		}
-		tempChain.AddTransaction(tx)
-		hash := tx.Hash()
-		transactionHashes[i] = hash[:]
+		transactionFees = uint64(fees)
+		view.AddTransaction(tx)
 	}
-	transactionHashes[len(transactions)] = coinbase.Hash().Bytes()
-	allTransactionsHash := crypto.HashBytes(util.ConcatByteSlices(transactionHashes))
+
+	// Validate coinbase transaction
+	coinbase := blk.Coinbase
+	if err := pow.ValidateCoinbaseTransaction(chn, coinbase, transactionFees); err != nil {
+		return err
+	}
+	allTransactionsHash := block.ComputeAllTransactionsHash(header.ProtocolVersion, transactions, coinbase)
 	if bytes.Compare(allTransactionsHash[:], header.AllTransactionsHash[:]) != 0 {
-		return false
+		return consensus.ErrBadMerkleRoot
+	}
+
+	return nil
+}
+
+// Returns nil if a block header is valid given the hash of the previous block and the number of the new block
+// Only checks the linkage and proof of work of the header, not the transactions it commits to
+func (pow *Pow) ValidateBlockHeader(previousBlockHash common.Hash, blockNum int, header *block.BlockHeader) error {
+	// Protocol version is one this node understands
+	if header.ProtocolVersion > protocol.CurrentProtocolVersion {
+		return consensus.ErrBadVersion
+	}
+
+	// Blocks after genesis must commit to their transactions with a Merkle tree
+	if blockNum > 0 && header.ProtocolVersion < protocol.MerkleTreeProtocolVersion {
+		return consensus.ErrBadVersion
+	}
+
+	// PreviousBlockHash corresponds to last block
+	if bytes.Compare(previousBlockHash[:], header.PreviousBlockHash[:]) != 0 {
+		return consensus.ErrBadPrevHash
 	}
 
-	blockNum := prevBlockNum + 1
 	targetHeader := protocol.ComputeTarget(blockNum)
 	// Check that the selected target is correct
 	if bytes.Compare(targetHeader[:], header.Target[:]) != 0 {
-		return false
+		return consensus.ErrBadTarget
 	}
 
 	headerHash := header.Hash()
 	target := targetHeader.FullHash()
 	// Check that the computed hash is valid based on the target
 	if bytes.Compare(headerHash[:], target[:]) >= 0 {
-		return false
+		return consensus.ErrInsufficientWork
 	}
 
-	return true
+	return nil
 }
 
 // Signs a transaction input
@@ -1,9 +1,18 @@
 package pow
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
 	"testing"
 
 	"github.com/AndrewCLu/TestcoinNode/account"
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/chain"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/consensus"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
 	"github.com/AndrewCLu/TestcoinNode/transaction"
 	"github.com/AndrewCLu/TestcoinNode/util"
 )
@@ -12,7 +21,7 @@ func TestSignVerifyTransactionInput(t *testing.T) {
 	pow, _ := New()
 
 	act, _ := account.New()
-	amount := util.Float64UnitToUnit64Unit(10.0)
+	amount := uint64(util.MustParseAmount("10"))
 
 	output := &transaction.TransactionOutput{ReceiverAddress: act.Address, Amount: amount}
 	tx, success := transaction.New(
@@ -36,3 +45,302 @@ func TestSignVerifyTransactionInput(t *testing.T) {
 		t.Fatalf(`Failed to verify the signature of a new coinbase transaction.`)
 	}
 }
+
+// Tests that a coinbase may collect the block reward plus fees but no more
+func TestValidateCoinbaseTransaction(t *testing.T) {
+	pow, _ := New()
+	act, _ := account.New()
+
+	chn := newTestChain(act)
+	_, lastBlockNum, _ := chn.GetLastBlockInfo()
+
+	reward := chn.Params().ComputeBlockReward(lastBlockNum + 1)
+	if pow.ValidateCoinbaseTransaction(chn, newCoinbase(act.Address, reward), 0) != nil {
+		t.Fatalf(`Rejected coinbase collecting the block reward`)
+	}
+	if pow.ValidateCoinbaseTransaction(chn, newCoinbase(act.Address, reward+5), 5) != nil {
+		t.Fatalf(`Rejected coinbase collecting the block reward and fees`)
+	}
+	if err := pow.ValidateCoinbaseTransaction(chn, newCoinbase(act.Address, reward+6), 5); !errors.Is(err, consensus.ErrCoinbaseTooLarge) {
+		t.Fatalf(`Accepted coinbase collecting more than the block reward and fees`)
+	}
+}
+
+// Creates a coinbase transaction paying amount to address
+func newCoinbase(address common.Address, amount uint64) *transaction.Transaction {
+	output := &transaction.TransactionOutput{ReceiverAddress: address, Amount: amount}
+	coinbase, _ := transaction.New(
+		[]*transaction.TransactionInput{},
+		[]*transaction.TransactionOutput{output},
+	)
+
+	return coinbase
+}
+
+// Creates a chain whose genesis coinbase pays reward to the account and can be spent in the next block
+func newTestChain(act *account.Account) *chain.Chain {
+	chn := newGenesisChain(act)
+	addFillerBlocks(chn, chn.Params().CoinbaseMaturity-1)
+
+	return chn
+}
+
+// Creates a chain holding only a genesis block whose coinbase pays reward to the account
+func newGenesisChain(act *account.Account) *chain.Chain {
+	chn, _ := chain.New(&protocol.TestNetParams)
+	genesisCoinbase := newCoinbase(act.Address, chn.Params().ComputeBlockReward(0))
+	genesisBlock, _ := block.New(common.Hash{}, 0, []*transaction.Transaction{}, genesisCoinbase)
+	chn.Initialize(genesisBlock)
+
+	return chn
+}
+
+// Adds empty blocks to the chain without validation, each paying its reward to an address unique to the block
+func addFillerBlocks(chn *chain.Chain, numBlocks int) {
+	for i := 0; i < numBlocks; i++ {
+		prevHash, lastBlockNum, _ := chn.GetLastBlockInfo()
+		coinbase := newCoinbase(common.Address{byte(lastBlockNum + 1)}, chn.Params().ComputeBlockReward(lastBlockNum+1))
+		blk, _ := block.New(prevHash, lastBlockNum+1, []*transaction.Transaction{}, coinbase)
+		chn.AddBlock(blk)
+	}
+}
+
+// Creates a transaction spending the output at ptr, owned by act, into outputs with the given amounts
+func newSpend(pow *Pow, act *account.Account, ptr *transaction.TransactionOutputPointer, amounts ...uint64) *transaction.Transaction {
+	verification := &transaction.TransactionInputVerification{
+		Signature:        pow.SignInput(act.PrivateKey, ptr),
+		EncodedPublicKey: act.PublicKey,
+	}
+	input := &transaction.TransactionInput{
+		OutputPointer:      ptr,
+		VerificationLength: uint16(len(verification.Bytes())),
+		Verification:       verification,
+	}
+
+	outputs := []*transaction.TransactionOutput{}
+	for _, amount := range amounts {
+		outputs = append(outputs, &transaction.TransactionOutput{ReceiverAddress: act.Address, Amount: amount})
+	}
+	tx, _ := transaction.New([]*transaction.TransactionInput{input}, outputs)
+
+	return tx
+}
+
+// Tests that transactions whose values are out of range or whose totals wrap around are rejected
+func TestValidateTransactionValues(t *testing.T) {
+	pow, _ := New()
+	act, _ := account.New()
+	chn := newTestChain(act)
+	utxos, _ := chn.GetUnspentTransactions(act.Address)
+	ptr := utxos[0]
+	reward := chn.Params().ComputeBlockReward(0)
+
+	if pow.ValidatePendingTransaction(chn, newSpend(pow, act, ptr, reward-1)) != nil {
+		t.Fatalf(`Rejected a valid transaction`)
+	}
+
+	// Outputs that wrap around to a total smaller than the input would create money
+	wrapping := newSpend(pow, act, ptr, math.MaxUint64, 2)
+	if pow.ValidatePendingTransaction(chn, wrapping) == nil {
+		t.Fatalf(`Accepted a transaction whose output total overflows`)
+	}
+	if _, ok := chn.GetPendingTransactionFee(wrapping); ok {
+		t.Fatalf(`Computed a fee for a transaction whose output total overflows`)
+	}
+
+	if err := pow.ValidatePendingTransaction(chn, newSpend(pow, act, ptr, 0)); !errors.Is(err, consensus.ErrZeroOutput) {
+		t.Fatalf(`Accepted a transaction with a zero value output`)
+	}
+	if err := pow.ValidatePendingTransaction(chn, newSpend(pow, act, ptr, protocol.MaxOutputValue+1)); !errors.Is(err, consensus.ErrOutputTooLarge) {
+		t.Fatalf(`Accepted a transaction with an output above the maximum output value`)
+	}
+
+	overspending := newSpend(pow, act, ptr, reward, 1)
+	if err := pow.ValidatePendingTransaction(chn, overspending); !errors.Is(err, consensus.ErrOutputsExceedInputs) {
+		t.Fatalf(`Accepted a transaction whose outputs exceed its inputs`)
+	}
+	if _, ok := chn.GetPendingTransactionFee(overspending); ok {
+		t.Fatalf(`Computed a fee for a transaction whose outputs exceed its inputs`)
+	}
+}
+
+// Tests that a coinbase cannot claim a reward plus fees that overflows or exceeds the maximum output value
+func TestValidateCoinbaseOverflow(t *testing.T) {
+	pow, _ := New()
+	act, _ := account.New()
+	chn := newTestChain(act)
+
+	if err := pow.ValidateCoinbaseTransaction(chn, newCoinbase(act.Address, 1), math.MaxUint64); !errors.Is(err, consensus.ErrFeesOverflow) {
+		t.Fatalf(`Accepted a coinbase whose reward plus fees overflows`)
+	}
+
+	// Fees never let a coinbase output exceed the maximum output value
+	if err := pow.ValidateCoinbaseTransaction(chn, newCoinbase(act.Address, protocol.MaxOutputValue+1), protocol.MaxOutputValue); !errors.Is(err, consensus.ErrOutputTooLarge) {
+		t.Fatalf(`Accepted a coinbase with an output above the maximum output value`)
+	}
+}
+
+// Tests that rejected transactions report the reason and the offending input
+func TestValidateTransactionErrors(t *testing.T) {
+	pow, _ := New()
+	act, _ := account.New()
+	other, _ := account.New()
+	chn := newTestChain(act)
+	utxos, _ := chn.GetUnspentTransactions(act.Address)
+	ptr := utxos[0]
+	reward := chn.Params().ComputeBlockReward(0)
+
+	// The second input spends the same output as the first
+	doubleSpend := newSpend(pow, act, ptr, reward-1)
+	doubleSpend.Inputs = append(doubleSpend.Inputs, doubleSpend.Inputs[0])
+	err := pow.ValidatePendingTransaction(chn, doubleSpend)
+	var inputErr *consensus.InputError
+	if !errors.Is(err, consensus.ErrDoubleSpend) || !errors.As(err, &inputErr) || inputErr.Index != 1 {
+		t.Fatalf(`Expected a double spend at input 1, got %v`, err)
+	}
+
+	// A signature by a key that does not match the public key fails verification
+	badSignature := newSpend(pow, act, ptr, reward-1)
+	badSignature.Inputs[0].Verification.Signature = pow.SignInput(other.PrivateKey, ptr)
+	err = pow.ValidatePendingTransaction(chn, badSignature)
+	if !errors.Is(err, consensus.ErrBadSignature) || !errors.As(err, &inputErr) || inputErr.Index != 0 {
+		t.Fatalf(`Expected a bad signature at input 0, got %v`, err)
+	}
+
+	// An output that does not exist cannot be spent
+	missing := &transaction.TransactionOutputPointer{TransactionHash: ptr.TransactionHash, OutputIndex: 1}
+	if err := pow.ValidatePendingTransaction(chn, newSpend(pow, act, missing, 1)); !errors.Is(err, consensus.ErrMissingInput) {
+		t.Fatalf(`Expected a missing input, got %v`, err)
+	}
+}
+
+// Tests that rejected blocks report the reason and the offending transaction
+func TestValidateBlockErrors(t *testing.T) {
+	pow, _ := New()
+	act, _ := account.New()
+	chn := newTestChain(act)
+	prevHash, lastBlockNum, _ := chn.GetLastBlockInfo()
+	blockNum := lastBlockNum + 1
+	coinbase := newCoinbase(act.Address, chn.Params().ComputeBlockReward(blockNum))
+
+	blk, _ := block.New(common.Hash{}, blockNum, []*transaction.Transaction{}, coinbase)
+	if err := pow.ValidateBlock(chn, blk); !errors.Is(err, consensus.ErrBadPrevHash) {
+		t.Fatalf(`Expected a bad previous hash, got %v`, err)
+	}
+
+	// An unsolved header almost certainly does not meet the target
+	blk, _ = block.New(prevHash, blockNum, []*transaction.Transaction{}, coinbase)
+	blk.Header.Nonce = solveHeader(t, blk.Header) + 1
+	for pow.ValidateBlockHeader(prevHash, blockNum, blk.Header) == nil {
+		blk.Header.Nonce += 1
+	}
+	if err := pow.ValidateBlock(chn, blk); !errors.Is(err, consensus.ErrInsufficientWork) {
+		t.Fatalf(`Expected insufficient work, got %v`, err)
+	}
+
+	// A block with an invalid transaction names it
+	utxos, _ := chn.GetUnspentTransactions(act.Address)
+	spend := newSpend(pow, act, utxos[0], 0)
+	blk, _ = block.New(prevHash, blockNum, []*transaction.Transaction{spend}, coinbase)
+	blk.Header.Nonce = solveHeader(t, blk.Header)
+	err := pow.ValidateBlock(chn, blk)
+	var txErr *consensus.TransactionError
+	if !errors.Is(err, consensus.ErrZeroOutput) || !errors.As(err, &txErr) || !txErr.Hash.Equal(spend.Hash()) {
+		t.Fatalf(`Expected a zero output in transaction %v, got %v`, spend.Hash().Hex(), err)
+	}
+
+	// A block using a protocol version from before Merkle tree commitments is refused
+	blk, _ = block.New(prevHash, blockNum, []*transaction.Transaction{}, coinbase)
+	blk.Header.ProtocolVersion = protocol.MerkleTreeProtocolVersion - 1
+	blk.Header.AllTransactionsHash = block.ComputeAllTransactionsHash(blk.Header.ProtocolVersion, blk.Body, blk.Coinbase)
+	blk.Header.Nonce = solveHeader(t, blk.Header)
+	if err := pow.ValidateBlock(chn, blk); !errors.Is(err, consensus.ErrBadVersion) {
+		t.Fatalf(`Expected a bad version, got %v`, err)
+	}
+
+	// A header committing to different transactions does not match the body
+	blk, _ = block.New(prevHash, blockNum, []*transaction.Transaction{}, coinbase)
+	blk.Header.AllTransactionsHash = common.Hash{}
+	blk.Header.Nonce = solveHeader(t, blk.Header)
+	if err := pow.ValidateBlock(chn, blk); !errors.Is(err, consensus.ErrBadMerkleRoot) {
+		t.Fatalf(`Expected a bad merkle root, got %v`, err)
+	}
+}
+
+// Tests that a block spending a coinbase before it has enough confirmations is rejected
+func TestValidateBlockImmatureCoinbase(t *testing.T) {
+	pow, _ := New()
+	act, _ := account.New()
+	chn := newGenesisChain(act)
+	utxos, _ := chn.GetUnspentTransactions(act.Address)
+	spend := newSpend(pow, act, utxos[0], chn.Params().ComputeBlockReward(0)-1)
+
+	newBlock := func() *block.Block {
+		prevHash, lastBlockNum, _ := chn.GetLastBlockInfo()
+		coinbase := newCoinbase(act.Address, chn.Params().ComputeBlockReward(lastBlockNum+1)+1)
+		blk, _ := block.New(prevHash, lastBlockNum+1, []*transaction.Transaction{spend}, coinbase)
+		blk.Header.Nonce = solveHeader(t, blk.Header)
+		return blk
+	}
+
+	// The genesis coinbase cannot be spent one block before it matures
+	addFillerBlocks(chn, chn.Params().CoinbaseMaturity-2)
+	err := pow.ValidateBlock(chn, newBlock())
+	var txErr *consensus.TransactionError
+	if !errors.Is(err, consensus.ErrImmatureCoinbase) || !errors.As(err, &txErr) || !txErr.Hash.Equal(spend.Hash()) {
+		t.Fatalf(`Expected an immature coinbase in transaction %v, got %v`, spend.Hash().Hex(), err)
+	}
+
+	// The genesis coinbase can be spent in the block whose number equals the maturity depth
+	addFillerBlocks(chn, 1)
+	if err := pow.ValidateBlock(chn, newBlock()); err != nil {
+		t.Fatalf(`Rejected a block spending a mature coinbase: %v`, err)
+	}
+}
+
+// Returns a nonce that solves the proof of work of a header
+func solveHeader(t *testing.T, header *block.BlockHeader) uint32 {
+	solved := *header
+	target := solved.Target.FullHash()
+	for nonce := uint32(0); nonce < math.MaxUint32; nonce++ {
+		solved.Nonce = nonce
+		hash := solved.Hash()
+		if bytes.Compare(hash[:], target[:]) < 0 {
+			return nonce
+		}
+	}
+
+	t.Fatalf(`Failed to solve header`)
+	return 0
+}
+
+// Benchmarks validating a spend by an address that owns many unspent outputs
+// Validation looks up the spent output directly, so its cost should not grow with the number of outputs
+func BenchmarkValidatePendingTransaction(b *testing.B) {
+	for _, numUtxos := range []int{1, 100, 10000} {
+		b.Run(fmt.Sprintf("utxos=%v", numUtxos), func(b *testing.B) {
+			pow, _ := New()
+			act, _ := account.New()
+			chn := newTestChain(act)
+
+			// Split the genesis reward into many outputs owned by the account
+			utxos, _ := chn.GetUnspentTransactions(act.Address)
+			amounts := []uint64{}
+			for i := 0; i < numUtxos; i++ {
+				amounts = append(amounts, 1000)
+			}
+			funding := newSpend(pow, act, utxos[0], amounts...)
+			chn.AddTransaction(funding)
+
+			ptr := &transaction.TransactionOutputPointer{TransactionHash: funding.Hash(), OutputIndex: uint16(numUtxos - 1)}
+			spend := newSpend(pow, act, ptr, 999)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := pow.ValidatePendingTransaction(chn, spend); err != nil {
+					b.Fatalf(`Rejected a valid spend: %v`, err)
+				}
+			}
+		})
+	}
+}
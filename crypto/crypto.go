@@ -2,35 +2,47 @@ package crypto
 
 import (
 	"crypto/sha256"
-	"fmt"
 
 	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/logging"
 )
 
+var log = logging.Get("crypto")
+
 // Generates digital signature, including a 32 byte address, public key, and private key
 func NewDigitalSignatureKeys() (encodedPublicKey []byte, encodedPrivateKey []byte, err error) {
 	publicKey, privateKey, err := newECDSAKeyPair()
 	if err != nil {
-		fmt.Println(err)
+		log.Error("Failed to generate key pair", "err", err)
 		return nil, nil, err
 	}
 
 	// Encode keys using x509
 	encodedPublicKey, err = encodePublicKey(publicKey)
 	if err != nil {
-		fmt.Println(err)
+		log.Error("Failed to encode public key", "err", err)
 		return nil, nil, err
 	}
 
 	encodedPrivateKey, err = encodePrivateKey(privateKey)
 	if err != nil {
-		fmt.Println(err)
+		log.Error("Failed to encode private key", "err", err)
 		return nil, nil, err
 	}
 
 	return encodedPublicKey, encodedPrivateKey, nil
 }
 
+// Given an x509 encoded private key, returns the corresponding x509 encoded public key
+func PublicKeyFromPrivateKey(encodedPrivateKey []byte) (encodedPublicKey []byte, err error) {
+	privateKey, err := decodePrivateKey(encodedPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePublicKey(&privateKey.PublicKey)
+}
+
 // Hashes bytes using SHA256 and returns the corresponging Hash
 func HashBytes(bytes []byte) common.Hash {
 	hashBytes := sha256.Sum256(bytes)
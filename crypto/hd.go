@@ -0,0 +1,304 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+const (
+	HardenedKeyStart    = 0x80000000 // Child numbers at or above this value use hardened derivation
+	ChainCodeLength     = 32         // The length of the chain code of an extended key
+	PrivateScalarLength = 32         // The length of a serialized private key scalar
+	CompressedKeyLength = 33         // The length of a compressed public key point
+	FingerprintLength   = 4          // The length of the fingerprint identifying a parent key
+
+	extendedKeyLength = 4 + 1 + FingerprintLength + 4 + ChainCodeLength + CompressedKeyLength // The length of a serialized extended key
+	minSeedLength     = 16                                                                    // The minimum number of bytes in a master seed
+	maxSeedLength     = 64                                                                    // The maximum number of bytes in a master seed
+)
+
+var (
+	masterKeyHMACKey = []byte("Testcoin seed") // The HMAC key used to derive a master key from a seed
+
+	privateKeyVersion = []byte{0x74, 0x70, 0x72, 0x76} // Version bytes of a serialized extended private key
+	publicKeyVersion  = []byte{0x74, 0x70, 0x75, 0x62} // Version bytes of a serialized extended public key
+
+	ErrInvalidSeedLength        = errors.New("seed length must be between 16 and 64 bytes")
+	ErrInvalidChild             = errors.New("derived key is invalid, use the next child number")
+	ErrDeriveHardenedFromPublic = errors.New("cannot derive a hardened child from an extended public key")
+	ErrInvalidExtendedKey       = errors.New("invalid serialized extended key")
+)
+
+// An extended key is a P-256 key together with the chain code needed to derive child keys from it
+// Private extended keys can derive both hardened and non-hardened children, while public extended keys
+// can only derive the public keys of non-hardened children
+type ExtendedKey struct {
+	Key               []byte                  // A 32 byte private scalar or a 33 byte compressed public key
+	ChainCode         []byte                  // Extra entropy used when deriving children
+	Depth             uint8                   // Number of derivations from the master key
+	ParentFingerprint [FingerprintLength]byte // Identifies the parent this key was derived from
+	ChildNumber       uint32                  // The index this key was derived at from its parent
+	IsPrivate         bool                    // True if Key is a private scalar
+}
+
+// Derives a master extended private key from a seed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < minSeedLength || len(seed) > maxSeedLength {
+		return nil, ErrInvalidSeedLength
+	}
+
+	mac := hmac.New(sha512.New, masterKeyHMACKey)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	scalar := new(big.Int).SetBytes(sum[:PrivateScalarLength])
+	if scalar.Sign() == 0 || scalar.Cmp(elliptic.P256().Params().N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	masterKey := ExtendedKey{
+		Key:       sum[:PrivateScalarLength],
+		ChainCode: sum[PrivateScalarLength:],
+		IsPrivate: true,
+	}
+
+	return &masterKey, nil
+}
+
+// Derives the child extended key at the given index
+// Indexes at or above HardenedKeyStart derive hardened children, which requires a private extended key
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	hardened := index >= HardenedKeyStart
+	if hardened && !k.IsPrivate {
+		return nil, ErrDeriveHardenedFromPublic
+	}
+
+	publicKey := k.compressedPublicKey()
+
+	// Hardened children commit to the private key, non-hardened children only to the public key
+	var data []byte
+	if hardened {
+		data = append([]byte{0}, k.Key...)
+	} else {
+		data = append([]byte{}, publicKey...)
+	}
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+	data = append(data, indexBytes...)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	curve := elliptic.P256()
+	order := curve.Params().N
+	tweak := new(big.Int).SetBytes(sum[:PrivateScalarLength])
+	if tweak.Cmp(order) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	var childKey []byte
+	if k.IsPrivate {
+		scalar := new(big.Int).SetBytes(k.Key)
+		scalar.Add(scalar, tweak)
+		scalar.Mod(scalar, order)
+		if scalar.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+
+		childKey = scalar.FillBytes(make([]byte, PrivateScalarLength))
+	} else {
+		x, y := elliptic.UnmarshalCompressed(curve, k.Key)
+		if x == nil {
+			return nil, ErrInvalidExtendedKey
+		}
+
+		tweakX, tweakY := curve.ScalarBaseMult(sum[:PrivateScalarLength])
+		childX, childY := curve.Add(x, y, tweakX, tweakY)
+		if childX.Sign() == 0 && childY.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+
+		childKey = elliptic.MarshalCompressed(curve, childX, childY)
+	}
+
+	child := ExtendedKey{
+		Key:         childKey,
+		ChainCode:   sum[PrivateScalarLength:],
+		Depth:       k.Depth + 1,
+		ChildNumber: index,
+		IsPrivate:   k.IsPrivate,
+	}
+	copy(child.ParentFingerprint[:], HashBytes(publicKey).Bytes()[:FingerprintLength])
+
+	return &child, nil
+}
+
+// Derives the descendant extended key by following a path of child indexes
+func (k *ExtendedKey) DerivePath(path []uint32) (*ExtendedKey, error) {
+	current := k
+	for _, index := range path {
+		child, err := current.Child(index)
+		if err != nil {
+			return nil, err
+		}
+		current = child
+	}
+
+	return current, nil
+}
+
+// Returns the extended public key corresponding to this extended key
+// The extended public key can derive the public keys of all non-hardened descendants
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.IsPrivate {
+		return k
+	}
+
+	publicKey := ExtendedKey{
+		Key:               k.compressedPublicKey(),
+		ChainCode:         k.ChainCode,
+		Depth:             k.Depth,
+		ParentFingerprint: k.ParentFingerprint,
+		ChildNumber:       k.ChildNumber,
+		IsPrivate:         false,
+	}
+
+	return &publicKey
+}
+
+// Erases the private scalar of an extended private key from memory
+// The key cannot be used afterwards, so it should be neutered first if its public key is still needed
+func (k *ExtendedKey) Zero() {
+	if !k.IsPrivate {
+		return
+	}
+
+	for i := range k.Key {
+		k.Key[i] = 0
+	}
+	k.Key = nil
+}
+
+// Returns the x509 encoded key pair of this extended key
+// The encoded private key is nil for extended public keys
+func (k *ExtendedKey) EncodedKeys() (encodedPublicKey []byte, encodedPrivateKey []byte, err error) {
+	curve := elliptic.P256()
+
+	if !k.IsPrivate {
+		x, y := elliptic.UnmarshalCompressed(curve, k.Key)
+		if x == nil {
+			return nil, nil, ErrInvalidExtendedKey
+		}
+
+		encodedPublicKey, err = encodePublicKey(&ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+		if err != nil {
+			log.Error("Failed to encode extended public key", "err", err)
+			return nil, nil, err
+		}
+
+		return encodedPublicKey, nil, nil
+	}
+
+	privateKey := new(ecdsa.PrivateKey)
+	privateKey.Curve = curve
+	privateKey.D = new(big.Int).SetBytes(k.Key)
+	privateKey.X, privateKey.Y = curve.ScalarBaseMult(k.Key)
+
+	encodedPublicKey, err = encodePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		log.Error("Failed to encode extended key public key", "err", err)
+		return nil, nil, err
+	}
+
+	encodedPrivateKey, err = encodePrivateKey(privateKey)
+	if err != nil {
+		log.Error("Failed to encode extended private key", "err", err)
+		return nil, nil, err
+	}
+
+	return encodedPublicKey, encodedPrivateKey, nil
+}
+
+// Serializes an extended key into a hex string
+func (k *ExtendedKey) String() string {
+	childNumberBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(childNumberBytes, k.ChildNumber)
+
+	version := publicKeyVersion
+	key := k.Key
+	if k.IsPrivate {
+		version = privateKeyVersion
+		key = append([]byte{0}, k.Key...)
+	}
+
+	serialized := make([]byte, 0, extendedKeyLength)
+	serialized = append(serialized, version...)
+	serialized = append(serialized, k.Depth)
+	serialized = append(serialized, k.ParentFingerprint[:]...)
+	serialized = append(serialized, childNumberBytes...)
+	serialized = append(serialized, k.ChainCode...)
+	serialized = append(serialized, key...)
+
+	return hex.EncodeToString(serialized)
+}
+
+// Parses an extended key serialized with String
+func ParseExtendedKey(encoded string) (*ExtendedKey, error) {
+	serialized, err := hex.DecodeString(encoded)
+	if err != nil || len(serialized) != extendedKeyLength {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	version := serialized[:4]
+	isPrivate := bytes.Equal(version, privateKeyVersion)
+	if !isPrivate && !bytes.Equal(version, publicKeyVersion) {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	k := ExtendedKey{
+		Depth:       serialized[4],
+		ChildNumber: binary.BigEndian.Uint32(serialized[9:13]),
+		ChainCode:   serialized[13 : 13+ChainCodeLength],
+		IsPrivate:   isPrivate,
+	}
+	copy(k.ParentFingerprint[:], serialized[5:9])
+
+	keyBytes := serialized[13+ChainCodeLength:]
+	if isPrivate {
+		if keyBytes[0] != 0 {
+			return nil, ErrInvalidExtendedKey
+		}
+		scalar := new(big.Int).SetBytes(keyBytes[1:])
+		if scalar.Sign() == 0 || scalar.Cmp(elliptic.P256().Params().N) >= 0 {
+			return nil, ErrInvalidExtendedKey
+		}
+		k.Key = keyBytes[1:]
+	} else {
+		if x, _ := elliptic.UnmarshalCompressed(elliptic.P256(), keyBytes); x == nil {
+			return nil, ErrInvalidExtendedKey
+		}
+		k.Key = keyBytes
+	}
+
+	return &k, nil
+}
+
+// Returns the compressed public key point of this extended key
+func (k *ExtendedKey) compressedPublicKey() []byte {
+	if !k.IsPrivate {
+		return k.Key
+	}
+
+	curve := elliptic.P256()
+	x, y := curve.ScalarBaseMult(k.Key)
+
+	return elliptic.MarshalCompressed(curve, x, y)
+}
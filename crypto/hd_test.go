@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+var testSeed = []byte("testcoin hd wallet test seed!!!!")
+
+// Tests that deriving keys from the same seed always yields the same keys
+func TestDeterministicDerivation(t *testing.T) {
+	masterA, err := NewMasterKey(testSeed)
+	if err != nil {
+		t.Fatalf(`Failed to create master key: %v`, err)
+	}
+	masterB, _ := NewMasterKey(testSeed)
+
+	path := []uint32{HardenedKeyStart, 0, 5}
+	childA, err := masterA.DerivePath(path)
+	if err != nil {
+		t.Fatalf(`Failed to derive child key: %v`, err)
+	}
+	childB, _ := masterB.DerivePath(path)
+
+	if !bytes.Equal(childA.Key, childB.Key) || !bytes.Equal(childA.ChainCode, childB.ChainCode) {
+		t.Fatalf(`Keys derived from the same seed do not match`)
+	}
+
+	sibling, _ := masterA.DerivePath([]uint32{HardenedKeyStart, 0, 6})
+	if bytes.Equal(childA.Key, sibling.Key) {
+		t.Fatalf(`Keys derived at different indexes match`)
+	}
+}
+
+// Tests that non-hardened children derived from an extended public key match those derived from the private key
+func TestPublicDerivation(t *testing.T) {
+	master, _ := NewMasterKey(testSeed)
+	accountKey, _ := master.Child(HardenedKeyStart)
+	accountPublicKey := accountKey.Neuter()
+
+	for index := uint32(0); index < 5; index++ {
+		privateChild, _ := accountKey.Child(index)
+		publicChild, err := accountPublicKey.Child(index)
+		if err != nil {
+			t.Fatalf(`Failed to derive public child: %v`, err)
+		}
+
+		if !bytes.Equal(privateChild.Neuter().Key, publicChild.Key) {
+			t.Fatalf(`Public child %v does not match the neutered private child`, index)
+		}
+	}
+
+	if _, err := accountPublicKey.Child(HardenedKeyStart); err != ErrDeriveHardenedFromPublic {
+		t.Fatalf(`Derived a hardened child from an extended public key`)
+	}
+}
+
+// Tests that keys derived from an extended key can sign and verify
+func TestSignVerifyWithDerivedKeys(t *testing.T) {
+	master, _ := NewMasterKey(testSeed)
+	child, _ := master.DerivePath([]uint32{HardenedKeyStart, 0, 0})
+
+	encodedPublicKey, encodedPrivateKey, err := child.EncodedKeys()
+	if err != nil {
+		t.Fatalf(`Failed to encode derived keys: %v`, err)
+	}
+
+	watchOnlyPublicKey, _, _ := child.Neuter().EncodedKeys()
+	if !bytes.Equal(encodedPublicKey, watchOnlyPublicKey) {
+		t.Fatalf(`Encoded public key of neutered key does not match`)
+	}
+
+	message := []byte("Go TST")
+	signature, ok := SignByteArray(message, encodedPrivateKey)
+	if !ok {
+		t.Fatalf(`Failed to sign with derived private key`)
+	}
+
+	if !VerifyByteArray(message, encodedPublicKey, signature) {
+		t.Fatalf(`Failed to verify signature with derived public key`)
+	}
+}
+
+// Tests that extended keys survive serialization
+func TestExtendedKeySerialization(t *testing.T) {
+	master, _ := NewMasterKey(testSeed)
+	child, _ := master.DerivePath([]uint32{HardenedKeyStart, 1})
+
+	for _, key := range []*ExtendedKey{child, child.Neuter()} {
+		decoded, err := ParseExtendedKey(key.String())
+		if err != nil {
+			t.Fatalf(`Failed to parse extended key: %v`, err)
+		}
+
+		if decoded.String() != key.String() {
+			t.Fatalf(`Extended keys do not match. Original: %v, Decoded: %v`, key, decoded)
+		}
+	}
+
+	if _, err := ParseExtendedKey("74707276"); err != ErrInvalidExtendedKey {
+		t.Fatalf(`Parsed a truncated extended key`)
+	}
+}
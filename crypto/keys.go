@@ -5,7 +5,6 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
-	"fmt"
 )
 
 // Generates an ECDSA public private key pair
@@ -15,7 +14,7 @@ func newECDSAKeyPair() (publicKey *ecdsa.PublicKey, privateKey *ecdsa.PrivateKey
 	privateKey, err = ecdsa.GenerateKey(publicKeyCurve, rand.Reader)
 
 	if err != nil {
-		fmt.Println(err)
+		log.Error("Failed to generate ECDSA key", "err", err)
 		return nil, nil, err
 	}
 
@@ -28,7 +27,7 @@ func newECDSAKeyPair() (publicKey *ecdsa.PublicKey, privateKey *ecdsa.PrivateKey
 func encodePublicKey(key *ecdsa.PublicKey) (bytes []byte, err error) {
 	bytes, err = x509.MarshalPKIXPublicKey(key)
 	if err != nil {
-		fmt.Println(err)
+		log.Error("Failed to encode public key", "err", err)
 		return nil, err
 	}
 
@@ -39,7 +38,7 @@ func encodePublicKey(key *ecdsa.PublicKey) (bytes []byte, err error) {
 func decodePublicKey(bytes []byte) (key *ecdsa.PublicKey, err error) {
 	genericPublicKey, err := x509.ParsePKIXPublicKey(bytes)
 	if err != nil {
-		fmt.Println(err)
+		log.Warn("Failed to decode public key", "err", err)
 		return nil, err
 	}
 
@@ -52,7 +51,7 @@ func decodePublicKey(bytes []byte) (key *ecdsa.PublicKey, err error) {
 func encodePrivateKey(key *ecdsa.PrivateKey) (bytes []byte, err error) {
 	bytes, err = x509.MarshalECPrivateKey(key)
 	if err != nil {
-		fmt.Println(err)
+		log.Error("Failed to encode private key", "err", err)
 		return nil, err
 	}
 
@@ -63,7 +62,7 @@ func encodePrivateKey(key *ecdsa.PrivateKey) (bytes []byte, err error) {
 func decodePrivateKey(bytes []byte) (key *ecdsa.PrivateKey, err error) {
 	key, err = x509.ParseECPrivateKey(bytes)
 	if err != nil {
-		fmt.Println(err)
+		log.Warn("Failed to decode private key", "err", err)
 		return nil, err
 	}
 
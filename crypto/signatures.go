@@ -3,7 +3,6 @@ package crypto
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
-	"fmt"
 	"math/big"
 )
 
@@ -13,8 +12,9 @@ type ECDSASignature struct {
 	s big.Int
 }
 
+const SignatureScalarLength = 32 // The number of bytes used for each of r and s in an encoded signature
+
 // Converts a byte array into a ECDSASignature
-// TODO: Find a better way to convert this that places the conversion info inside the struct
 func BytesToECDSASignature(bytes []byte) *ECDSASignature {
 	rBytes := bytes[:len(bytes)/2]
 	sBytes := bytes[len(bytes)/2:]
@@ -28,7 +28,18 @@ func BytesToECDSASignature(bytes []byte) *ECDSASignature {
 }
 
 // Converts an ECDSASignature into bytes
+// r and s are left padded to a fixed width so the signature can be split back into them
 func (sig ECDSASignature) Bytes() []byte {
+	signatureBytes := make([]byte, 2*SignatureScalarLength)
+	sig.r.FillBytes(signatureBytes[:SignatureScalarLength])
+	sig.s.FillBytes(signatureBytes[SignatureScalarLength:])
+
+	return signatureBytes
+}
+
+// Converts an ECDSASignature into bytes with r and s at their minimal width, as encoded by earlier protocol versions
+// The split between r and s is not recorded, so these bytes cannot be converted back into a signature
+func (sig ECDSASignature) LegacyBytes() []byte {
 	rBytes := sig.r.Bytes()
 	sBytes := sig.s.Bytes()
 
@@ -45,7 +56,7 @@ func SignByteArray(bytes []byte, privateKey []byte) (signature *ECDSASignature,
 	// Must decode private key first
 	decodedPrivateKey, decodeErr := decodePrivateKey(privateKey)
 	if decodeErr != nil {
-		fmt.Println(decodeErr)
+		log.Warn("Cannot sign with an invalid private key", "err", decodeErr)
 		return nil, false
 	}
 
@@ -53,7 +64,7 @@ func SignByteArray(bytes []byte, privateKey []byte) (signature *ECDSASignature,
 
 	r, s, signErr := ecdsa.Sign(rand.Reader, decodedPrivateKey, hash.Bytes())
 	if signErr != nil {
-		fmt.Println(signErr)
+		log.Error("Failed to sign", "err", signErr)
 		return nil, false
 	}
 
@@ -71,7 +82,7 @@ func VerifyByteArray(bytes []byte, publicKey []byte, signature *ECDSASignature)
 	// Must decode public key first
 	decodedPublicKey, decodeErr := decodePublicKey(publicKey)
 	if decodeErr != nil {
-		fmt.Println(decodeErr)
+		log.Warn("Cannot verify with an invalid public key", "err", decodeErr)
 		return false
 	}
 
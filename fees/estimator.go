@@ -0,0 +1,192 @@
+package fees
+
+import (
+	"sync"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+)
+
+const (
+	DefaultMaxTargetBlocks  = 25    // The largest confirmation target, in blocks, that can be estimated
+	DefaultSuccessThreshold = 0.85  // The fraction of transactions in a bucket that must confirm within the target
+	DefaultDecay            = 0.998 // Historical data is multiplied by this each block so recent blocks count more
+	DefaultMinSamples       = 0.5   // The decayed number of transactions a group of buckets needs before it is used for an estimate
+
+	minBucketFeeRate = 1                       // The fee rate of the lowest bucket, in base units per byte
+	maxBucketFeeRate = 10 * 1000 * 1000 * 1000 // The fee rate of the highest bucket, in base units per byte
+	bucketSpacing    = 2                       // Each bucket's fee rate is this many times the previous bucket's
+)
+
+// A tracked transaction is a pending transaction the estimator is waiting to see confirmed
+type trackedTransaction struct {
+	bucket      int // The index of the fee rate bucket the transaction belongs to
+	entryHeight int // The block number of the tip when the transaction entered the pending pool
+}
+
+// An estimator tracks how many blocks transactions at various fee rates wait in the pending pool before
+// being confirmed, and estimates the fee rate needed to confirm a new transaction within a target number of blocks
+// Tracked state is guarded by a lock, since the pending pool and block processing update it concurrently
+type Estimator struct {
+	mu               sync.Mutex
+	Buckets          []uint64 // The lowest fee rate of each bucket, in ascending order
+	MaxTargetBlocks  int
+	SuccessThreshold float64
+	Decay            float64
+	MinSamples       float64
+
+	tracked         map[common.Hash]*trackedTransaction
+	observed        []float64   // Decayed count of confirmed transactions per bucket
+	confirmedWithin [][]float64 // Decayed count of transactions per bucket confirmed within each number of blocks
+	height          int         // The block number of the last processed block
+}
+
+// Creates an estimator with the default parameters
+func New() (*Estimator, bool) {
+	buckets := []uint64{}
+	for feeRate := uint64(minBucketFeeRate); feeRate <= maxBucketFeeRate; feeRate *= bucketSpacing {
+		buckets = append(buckets, feeRate)
+	}
+
+	confirmedWithin := make([][]float64, len(buckets))
+	for i := range confirmedWithin {
+		confirmedWithin[i] = make([]float64, DefaultMaxTargetBlocks)
+	}
+
+	estimator := Estimator{
+		Buckets:          buckets,
+		MaxTargetBlocks:  DefaultMaxTargetBlocks,
+		SuccessThreshold: DefaultSuccessThreshold,
+		Decay:            DefaultDecay,
+		MinSamples:       DefaultMinSamples,
+		tracked:          make(map[common.Hash]*trackedTransaction),
+		observed:         make([]float64, len(buckets)),
+		confirmedWithin:  confirmedWithin,
+	}
+
+	return &estimator, true
+}
+
+// Starts tracking a transaction that entered the pending pool while the tip was at the given block number
+func (e *Estimator) AddTransaction(txHash common.Hash, feeRate uint64, height int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.tracked[txHash]; ok {
+		return
+	}
+
+	e.tracked[txHash] = &trackedTransaction{
+		bucket:      e.bucketIndex(feeRate),
+		entryHeight: height,
+	}
+}
+
+// Stops tracking a transaction that left the pending pool without being confirmed
+func (e *Estimator) RemoveTransaction(txHash common.Hash) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.tracked, txHash)
+}
+
+// Records how long each tracked transaction in a newly connected block waited to be confirmed
+func (e *Estimator) ProcessBlock(height int, txHashes []common.Hash) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if height <= e.height {
+		return
+	}
+	e.height = height
+
+	// Decay historical data so the estimate follows recent conditions
+	for bucket := range e.observed {
+		e.observed[bucket] *= e.Decay
+		for target := range e.confirmedWithin[bucket] {
+			e.confirmedWithin[bucket][target] *= e.Decay
+		}
+	}
+
+	for _, txHash := range txHashes {
+		tx, ok := e.tracked[txHash]
+		if !ok {
+			continue
+		}
+		delete(e.tracked, txHash)
+
+		blocksWaited := height - tx.entryHeight
+		if blocksWaited < 1 {
+			blocksWaited = 1
+		}
+
+		e.observed[tx.bucket] += 1
+		for target := blocksWaited - 1; target < e.MaxTargetBlocks; target++ {
+			e.confirmedWithin[tx.bucket][target] += 1
+		}
+	}
+}
+
+// Estimates the lowest fee rate, in base units per byte, at which a transaction is likely to be confirmed
+// within the target number of blocks
+// Returns a bool indicating success, which fails if the target is out of range or there is not enough data
+func (e *Estimator) EstimateFeeRate(targetBlocks int) (uint64, bool) {
+	if targetBlocks < 1 || targetBlocks > e.MaxTargetBlocks {
+		return 0, false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Transactions still pending after the target count as failures to confirm in time
+	failures := make([]float64, len(e.Buckets))
+	for _, tx := range e.tracked {
+		if e.height-tx.entryHeight >= targetBlocks {
+			failures[tx.bucket] += 1
+		}
+	}
+
+	// Starting from the highest fee rate, group buckets until they hold enough data, and
+	// keep lowering the estimate while each group confirms often enough
+	best := -1
+	var confirmed, total float64
+	for bucket := len(e.Buckets) - 1; bucket >= 0; bucket-- {
+		confirmed += e.confirmedWithin[bucket][targetBlocks-1]
+		total += e.observed[bucket] + failures[bucket]
+		if total < e.MinSamples {
+			continue
+		}
+
+		if confirmed/total < e.SuccessThreshold {
+			break
+		}
+
+		best = bucket
+		confirmed, total = 0, 0
+	}
+
+	if best == -1 {
+		return 0, false
+	}
+
+	return e.Buckets[best], true
+}
+
+// Returns the number of transactions the estimator is waiting to see confirmed
+func (e *Estimator) NumTracked() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.tracked)
+}
+
+// Returns the index of the bucket a fee rate belongs to
+func (e *Estimator) bucketIndex(feeRate uint64) int {
+	index := 0
+	for i, bucketFeeRate := range e.Buckets {
+		if feeRate >= bucketFeeRate {
+			index = i
+		}
+	}
+
+	return index
+}
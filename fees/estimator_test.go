@@ -0,0 +1,84 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+)
+
+// Returns a distinct hash for a synthetic transaction
+func testHash(i int) common.Hash {
+	return common.BytesToHash([]byte{byte(i >> 8), byte(i)})
+}
+
+// Tests that the estimate follows how long transactions at each fee rate waited to be confirmed
+func TestEstimateFeeRate(t *testing.T) {
+	estimator, _ := New()
+
+	if _, ok := estimator.EstimateFeeRate(1); ok {
+		t.Fatalf(`Estimated a fee rate without any data`)
+	}
+
+	// Transactions paying 1000 per byte confirm in the next block, those paying 10 wait five blocks
+	next := 0
+	for height := 0; height < 20; height++ {
+		confirmed := []common.Hash{}
+
+		estimator.AddTransaction(testHash(next), 1000, height)
+		confirmed = append(confirmed, testHash(next))
+		next++
+
+		if height >= 4 {
+			confirmed = append(confirmed, testHash(1000+height-4))
+		}
+		estimator.AddTransaction(testHash(1000+height), 10, height)
+
+		estimator.ProcessBlock(height+1, confirmed)
+	}
+
+	fast, ok := estimator.EstimateFeeRate(1)
+	if !ok || fast < 512 || fast > 1000 {
+		t.Fatalf(`Expected a next block estimate in the bucket of 1000, got %v`, fast)
+	}
+
+	slow, ok := estimator.EstimateFeeRate(5)
+	if !ok || slow < 8 || slow > 10 {
+		t.Fatalf(`Expected a five block estimate in the bucket of 10, got %v`, slow)
+	}
+
+	if _, ok := estimator.EstimateFeeRate(0); ok {
+		t.Fatalf(`Estimated a fee rate for a target of zero blocks`)
+	}
+	if _, ok := estimator.EstimateFeeRate(DefaultMaxTargetBlocks + 1); ok {
+		t.Fatalf(`Estimated a fee rate for a target beyond the maximum`)
+	}
+}
+
+// Tests that transactions stuck in the pending pool count against their fee rate
+func TestStuckTransactions(t *testing.T) {
+	estimator, _ := New()
+
+	// One transaction at 100 per byte confirms quickly, but many more never confirm
+	estimator.AddTransaction(testHash(0), 100, 0)
+	estimator.ProcessBlock(1, []common.Hash{testHash(0)})
+	for i := 1; i <= 10; i++ {
+		estimator.AddTransaction(testHash(i), 100, 1)
+	}
+	for height := 2; height <= 5; height++ {
+		estimator.ProcessBlock(height, []common.Hash{})
+	}
+
+	if _, ok := estimator.EstimateFeeRate(2); ok {
+		t.Fatalf(`Estimated a fee rate at which most transactions are stuck`)
+	}
+
+	for i := 1; i <= 10; i++ {
+		estimator.RemoveTransaction(testHash(i))
+	}
+	if estimator.NumTracked() != 0 {
+		t.Fatalf(`Expected no tracked transactions, got %v`, estimator.NumTracked())
+	}
+	if _, ok := estimator.EstimateFeeRate(2); !ok {
+		t.Fatalf(`Failed to estimate a fee rate after stuck transactions were removed`)
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/AndrewCLu/TestcoinNode
 
 go 1.17
+
+require golang.org/x/crypto v0.9.0
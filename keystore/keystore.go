@@ -0,0 +1,509 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AndrewCLu/TestcoinNode/account"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/logging"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	CurrentVersion = 1 // The version of the key file format written by this keystore
+
+	StandardScryptN = 1 << 18 // Scrypt cost parameter for stored keys, using 256MB of memory
+	StandardScryptP = 1       // Scrypt parallelization parameter for stored keys
+	LightScryptN    = 1 << 12 // Scrypt cost parameter for low memory devices and tests, using 4MB of memory
+	LightScryptP    = 6       // Scrypt parallelization parameter for low memory devices and tests
+	MaxScryptN      = 1 << 20 // The largest scrypt cost parameter a key file may ask for, using 1GB of memory
+	MaxScryptP      = 16      // The largest scrypt parallelization parameter a key file may ask for
+
+	scryptR         = 8  // Scrypt block size parameter
+	scryptKeyLength = 32 // Length of the derived AES-256 key
+	saltLength      = 32 // Length of the random KDF salt
+
+	cipherName    = "aes-256-gcm" // The authenticated cipher used to encrypt private keys
+	kdfName       = "scrypt"      // The memory-hard KDF used to derive encryption keys from passwords
+	keyFileSuffix = ".json"       // The suffix of key files in the keystore directory
+)
+
+var log = logging.Get("keystore")
+
+// A key file is the versioned JSON envelope an encrypted private key is stored in
+type KeyFile struct {
+	Version   int       `json:"version"`
+	Address   string    `json:"address"`
+	PublicKey string    `json:"publicKey"`
+	Crypto    KeyCrypto `json:"crypto"`
+}
+
+// Key crypto describes how the private key in a key file was encrypted
+type KeyCrypto struct {
+	Cipher     string    `json:"cipher"`
+	Ciphertext string    `json:"ciphertext"`
+	Nonce      string    `json:"nonce"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfParams"`
+}
+
+// KDF params are the scrypt parameters used to derive the encryption key from a password
+type KDFParams struct {
+	N         int    `json:"n"`
+	R         int    `json:"r"`
+	P         int    `json:"p"`
+	KeyLength int    `json:"keyLength"`
+	Salt      string `json:"salt"`
+}
+
+// A keystore stores account private keys in a directory, each encrypted with a password
+// Private keys are only decrypted while an account is unlocked
+// Accounts are identified by their human readable address on the keystore's network
+type KeyStore struct {
+	Directory     string
+	AddressPrefix string // The prefix of addresses on the network the keys belong to
+	ScryptN       int
+	ScryptP       int
+}
+
+// Creates a keystore in the given directory for the network with the given address prefix, using the standard scrypt parameters
+// Returns a bool indicating success
+func New(directory string, addressPrefix string) (*KeyStore, bool) {
+	return NewWithScryptParams(directory, addressPrefix, StandardScryptN, StandardScryptP)
+}
+
+// Creates a keystore in the given directory for the network with the given address prefix,
+// encrypting new keys with the given scrypt parameters
+// Returns a bool indicating success, which fails if the parameters are above MaxScryptN or MaxScryptP
+func NewWithScryptParams(directory string, addressPrefix string, scryptN int, scryptP int) (*KeyStore, bool) {
+	if !validScryptParams(scryptN, scryptR, scryptP) {
+		log.Warn("Scrypt parameters are out of range", "n", scryptN, "p", scryptP)
+		return nil, false
+	}
+
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		log.Error("Failed to create keystore directory", "dir", directory, "err", err)
+		return nil, false
+	}
+
+	keyStore := KeyStore{
+		Directory:     directory,
+		AddressPrefix: addressPrefix,
+		ScryptN:       scryptN,
+		ScryptP:       scryptP,
+	}
+
+	return &keyStore, true
+}
+
+// Encrypts an unlocked account's private key with a password and stores it
+// Returns a bool indicating success
+func (ks *KeyStore) StoreAccount(act *account.Account, password string) bool {
+	if act.IsLocked() {
+		log.Warn("Cannot store a locked account")
+		return false
+	}
+
+	keyFile, ok := ks.encryptKey(act, password)
+	if !ok {
+		return false
+	}
+
+	return ks.writeKeyFile(keyFile)
+}
+
+// Returns the human readable addresses of all accounts in the keystore
+func (ks *KeyStore) Addresses() []string {
+	entries, err := os.ReadDir(ks.Directory)
+	if err != nil {
+		log.Error("Failed to read keystore directory", "dir", ks.Directory, "err", err)
+		return nil
+	}
+
+	addresses := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, keyFileSuffix) {
+			continue
+		}
+
+		encoded := strings.TrimSuffix(name, keyFileSuffix)
+		if _, err := common.DecodeAddress(encoded, ks.AddressPrefix); err != nil {
+			continue
+		}
+		addresses = append(addresses, encoded)
+	}
+
+	return addresses
+}
+
+// Returns true if the keystore holds a key for the human readable address
+func (ks *KeyStore) HasAddress(encoded string) bool {
+	address, ok := ks.decodeAddress(encoded)
+	if !ok {
+		return false
+	}
+
+	_, err := os.Stat(ks.keyFilePath(address))
+	return err == nil
+}
+
+// Returns the locked account for a human readable address, which has a public key but no private key
+// Returns a bool indicating success
+func (ks *KeyStore) GetAccount(encoded string) (*account.Account, bool) {
+	keyFile, ok := ks.readKeyFile(encoded)
+	if !ok {
+		return nil, false
+	}
+
+	publicKey, err := hex.DecodeString(keyFile.PublicKey)
+	if err != nil {
+		log.Warn("Malformed key file public key", "address", encoded, "err", err)
+		return nil, false
+	}
+
+	return account.NewFromKeys(publicKey, nil), true
+}
+
+// Decrypts the private key of a human readable address, returning an unlocked account
+// The caller should call Lock on the account as soon as it is done signing
+// Returns a bool indicating success, which fails if the password is incorrect
+func (ks *KeyStore) Unlock(encoded string, password string) (*account.Account, bool) {
+	keyFile, ok := ks.readKeyFile(encoded)
+	if !ok {
+		return nil, false
+	}
+
+	return ks.decryptKey(keyFile, password)
+}
+
+// Unlocks an account, calls sign with it, and locks the account again before returning
+// Returns the result of sign, or false if the account could not be unlocked
+func (ks *KeyStore) SignWith(encoded string, password string, sign func(act *account.Account) bool) bool {
+	act, ok := ks.Unlock(encoded, password)
+	if !ok {
+		return false
+	}
+	defer act.Lock()
+
+	return sign(act)
+}
+
+// Re-encrypts the private key of a human readable address with a new password
+// Returns a bool indicating success, which fails if the old password is incorrect
+func (ks *KeyStore) ChangePassword(encoded string, oldPassword string, newPassword string) bool {
+	act, ok := ks.Unlock(encoded, oldPassword)
+	if !ok {
+		return false
+	}
+	defer act.Lock()
+
+	return ks.StoreAccount(act, newPassword)
+}
+
+// Exports the key of a human readable address as a key file encrypted with an export password
+// Returns a bool indicating success, which fails if the password is incorrect
+func (ks *KeyStore) Export(encoded string, password string, exportPassword string) ([]byte, bool) {
+	act, ok := ks.Unlock(encoded, password)
+	if !ok {
+		return nil, false
+	}
+	defer act.Lock()
+
+	keyFile, ok := ks.encryptKey(act, exportPassword)
+	if !ok {
+		return nil, false
+	}
+
+	keyJSON, err := json.MarshalIndent(keyFile, "", "  ")
+	if err != nil {
+		log.Error("Failed to encode key file", "err", err)
+		return nil, false
+	}
+
+	return keyJSON, true
+}
+
+// Imports a key file encrypted with an import password, storing it encrypted with a new password
+// Key files exported on another network are refused
+// Returns the imported human readable address and a bool indicating success
+func (ks *KeyStore) Import(keyJSON []byte, importPassword string, newPassword string) (string, bool) {
+	keyFile := new(KeyFile)
+	if err := json.Unmarshal(keyJSON, keyFile); err != nil {
+		log.Warn("Malformed key file", "err", err)
+		return "", false
+	}
+
+	act, ok := ks.decryptKey(keyFile, importPassword)
+	if !ok {
+		return "", false
+	}
+	defer act.Lock()
+
+	encoded := act.Address.Encode(ks.AddressPrefix)
+	if ks.HasAddress(encoded) {
+		log.Warn("Keystore already contains address", "address", encoded)
+		return "", false
+	}
+
+	if !ks.StoreAccount(act, newPassword) {
+		return "", false
+	}
+
+	return encoded, true
+}
+
+// Deletes the key of a human readable address from the keystore after checking the password
+// Returns a bool indicating success
+func (ks *KeyStore) Delete(encoded string, password string) bool {
+	act, ok := ks.Unlock(encoded, password)
+	if !ok {
+		return false
+	}
+	act.Lock()
+
+	if err := os.Remove(ks.keyFilePath(act.Address)); err != nil {
+		log.Error("Failed to delete key file", "address", encoded, "err", err)
+		return false
+	}
+
+	return true
+}
+
+// Encrypts the private key of an unlocked account into a key file
+func (ks *KeyStore) encryptKey(act *account.Account, password string) (*KeyFile, bool) {
+	keyFile := KeyFile{
+		Version:   CurrentVersion,
+		Address:   act.Address.Encode(ks.AddressPrefix),
+		PublicKey: hex.EncodeToString(act.PublicKey),
+	}
+
+	keyCrypto, ok := ks.seal(act.PrivateKey, keyFile.additionalData(), password)
+	if !ok {
+		return nil, false
+	}
+	keyFile.Crypto = *keyCrypto
+
+	return &keyFile, true
+}
+
+// Encrypts a secret with a key derived from a password, authenticating additionalData along with it
+func (ks *KeyStore) seal(secret []byte, additionalData []byte, password string) (*KeyCrypto, bool) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		log.Error("Failed to generate salt", "err", err)
+		return nil, false
+	}
+
+	params := KDFParams{
+		N:         ks.ScryptN,
+		R:         scryptR,
+		P:         ks.ScryptP,
+		KeyLength: scryptKeyLength,
+		Salt:      hex.EncodeToString(salt),
+	}
+
+	aead, ok := newAEAD(password, params)
+	if !ok {
+		return nil, false
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		log.Error("Failed to generate nonce", "err", err)
+		return nil, false
+	}
+
+	keyCrypto := KeyCrypto{
+		Cipher:     cipherName,
+		Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, secret, additionalData)),
+		Nonce:      hex.EncodeToString(nonce),
+		KDF:        kdfName,
+		KDFParams:  params,
+	}
+
+	return &keyCrypto, true
+}
+
+// Decrypts a secret sealed with a password, checking that additionalData was sealed along with it
+// Returns a bool indicating success, which fails if the password is incorrect or the envelope was modified
+func open(keyCrypto *KeyCrypto, additionalData []byte, password string) ([]byte, bool) {
+	if keyCrypto.Cipher != cipherName || keyCrypto.KDF != kdfName {
+		log.Warn("Unsupported key file cipher or KDF", "cipher", keyCrypto.Cipher, "kdf", keyCrypto.KDF)
+		return nil, false
+	}
+
+	ciphertext, ciphertextErr := hex.DecodeString(keyCrypto.Ciphertext)
+	nonce, nonceErr := hex.DecodeString(keyCrypto.Nonce)
+	if ciphertextErr != nil || nonceErr != nil {
+		log.Warn("Malformed key file")
+		return nil, false
+	}
+
+	aead, ok := newAEAD(password, keyCrypto.KDFParams)
+	if !ok {
+		return nil, false
+	}
+	if len(nonce) != aead.NonceSize() {
+		log.Warn("Malformed key file")
+		return nil, false
+	}
+
+	secret, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		log.Warn("Could not decrypt key file, the password may be incorrect")
+		return nil, false
+	}
+
+	return secret, true
+}
+
+// Decrypts the private key in a key file, returning an unlocked account
+func (ks *KeyStore) decryptKey(keyFile *KeyFile, password string) (*account.Account, bool) {
+	if keyFile.Version != CurrentVersion {
+		log.Warn("Unsupported key file version", "version", keyFile.Version)
+		return nil, false
+	}
+
+	publicKey, err := hex.DecodeString(keyFile.PublicKey)
+	if err != nil {
+		log.Warn("Malformed key file")
+		return nil, false
+	}
+
+	// The address must be encoded for this keystore's network and match the public key
+	if account.GetAddressFromPublicKey(publicKey).Encode(ks.AddressPrefix) != keyFile.Address {
+		log.Warn("Key file address does not match its public key or network", "address", keyFile.Address)
+		return nil, false
+	}
+
+	privateKey, ok := open(&keyFile.Crypto, keyFile.additionalData(), password)
+	if !ok {
+		return nil, false
+	}
+
+	derivedPublicKey, err := crypto.PublicKeyFromPrivateKey(privateKey)
+	if err != nil || !bytes.Equal(derivedPublicKey, publicKey) {
+		log.Warn("Decrypted private key does not match the key file public key", "address", keyFile.Address)
+		return nil, false
+	}
+
+	return account.NewFromKeys(publicKey, privateKey), true
+}
+
+// Derives an encryption key from a password and returns the authenticated cipher using it
+// Parameters are read from key files, so costs above the maximums are refused rather than exhausting memory
+func newAEAD(password string, params KDFParams) (cipher.AEAD, bool) {
+	if !validScryptParams(params.N, params.R, params.P) || params.KeyLength != scryptKeyLength {
+		log.Warn("Key file KDF parameters are out of range", "n", params.N, "r", params.R, "p", params.P)
+		return nil, false
+	}
+
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		log.Warn("Malformed key file salt", "err", err)
+		return nil, false
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, params.KeyLength)
+	if err != nil {
+		log.Error("Failed to derive key from password", "err", err)
+		return nil, false
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		log.Error("Failed to create cipher", "err", err)
+		return nil, false
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Error("Failed to create cipher", "err", err)
+		return nil, false
+	}
+
+	return aead, true
+}
+
+// Returns true if scrypt parameters are ones this keystore writes and is willing to read
+func validScryptParams(n int, r int, p int) bool {
+	return n > 1 && n <= MaxScryptN && n&(n-1) == 0 && r == scryptR && p >= 1 && p <= MaxScryptP
+}
+
+// Returns the data authenticated along with the private key, binding the ciphertext to its envelope
+func (keyFile *KeyFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("%v:%v:%v", keyFile.Version, keyFile.Address, keyFile.PublicKey))
+}
+
+// Writes a key file into the keystore directory, replacing any existing file for the address
+func (ks *KeyStore) writeKeyFile(keyFile *KeyFile) bool {
+	keyJSON, err := json.MarshalIndent(keyFile, "", "  ")
+	if err != nil {
+		log.Error("Failed to encode key file", "address", keyFile.Address, "err", err)
+		return false
+	}
+
+	// Write to a temporary file first so an existing key is never left half written
+	path := filepath.Join(ks.Directory, keyFile.Address+keyFileSuffix)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, keyJSON, 0600); err != nil {
+		log.Error("Failed to write key file", "address", keyFile.Address, "err", err)
+		return false
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		log.Error("Failed to write key file", "address", keyFile.Address, "err", err)
+		return false
+	}
+
+	return true
+}
+
+// Reads the key file of a human readable address from the keystore directory
+func (ks *KeyStore) readKeyFile(encoded string) (*KeyFile, bool) {
+	address, ok := ks.decodeAddress(encoded)
+	if !ok {
+		return nil, false
+	}
+
+	keyJSON, err := os.ReadFile(ks.keyFilePath(address))
+	if err != nil {
+		log.Warn("Failed to read key file", "address", encoded, "err", err)
+		return nil, false
+	}
+
+	keyFile := new(KeyFile)
+	if err := json.Unmarshal(keyJSON, keyFile); err != nil {
+		log.Warn("Malformed key file", "address", encoded, "err", err)
+		return nil, false
+	}
+
+	return keyFile, true
+}
+
+// Decodes a human readable address, rejecting addresses that are corrupted or belong to another network
+func (ks *KeyStore) decodeAddress(encoded string) (common.Address, bool) {
+	address, err := common.DecodeAddress(encoded, ks.AddressPrefix)
+	if err != nil {
+		log.Warn("Invalid address", "address", encoded, "err", err)
+		return common.Address{}, false
+	}
+
+	return address, true
+}
+
+// Returns the path of the key file of an address, which is named by its human readable form
+func (ks *KeyStore) keyFilePath(address common.Address) string {
+	return filepath.Join(ks.Directory, address.Encode(ks.AddressPrefix)+keyFileSuffix)
+}
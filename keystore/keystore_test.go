@@ -0,0 +1,179 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/account"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+)
+
+var testPrefix = protocol.TestNetParams.AddressPrefix
+
+// Creates a keystore in a temporary directory holding one new account
+func newTestKeyStore(t *testing.T, password string) (*KeyStore, *account.Account) {
+	ks, ok := NewWithScryptParams(t.TempDir(), testPrefix, LightScryptN, LightScryptP)
+	if !ok {
+		t.Fatalf(`Failed to create keystore`)
+	}
+
+	act, _ := account.New()
+	if !ks.StoreAccount(act, password) {
+		t.Fatalf(`Failed to store account`)
+	}
+
+	return ks, act
+}
+
+// Tests that a stored account can be unlocked with its password and signs with the original key
+func TestStoreUnlock(t *testing.T) {
+	ks, act := newTestKeyStore(t, "correct horse")
+
+	addresses := ks.Addresses()
+	if len(addresses) != 1 || addresses[0] != act.Address.Encode(testPrefix) {
+		t.Fatalf(`Keystore does not list the stored address`)
+	}
+
+	if _, ok := ks.Unlock(act.Address.Encode(testPrefix), "wrong horse"); ok {
+		t.Fatalf(`Unlocked account with the wrong password`)
+	}
+
+	unlocked, ok := ks.Unlock(act.Address.Encode(testPrefix), "correct horse")
+	if !ok {
+		t.Fatalf(`Failed to unlock account with the correct password`)
+	}
+	if !bytes.Equal(unlocked.PrivateKey, act.PrivateKey) {
+		t.Fatalf(`Unlocked private key does not match the stored key`)
+	}
+
+	if _, ok := ks.Unlock(act.Address.Encode(protocol.MainNetParams.AddressPrefix), "correct horse"); ok {
+		t.Fatalf(`Unlocked account with an address from another network`)
+	}
+
+	unlocked.Lock()
+	if !unlocked.IsLocked() {
+		t.Fatalf(`Account is not locked after calling Lock`)
+	}
+
+	locked, _ := ks.GetAccount(act.Address.Encode(testPrefix))
+	if !locked.IsLocked() || !bytes.Equal(locked.PublicKey, act.PublicKey) {
+		t.Fatalf(`Locked account does not match the stored account`)
+	}
+}
+
+// Tests that an account is only unlocked while signing
+func TestSignWith(t *testing.T) {
+	ks, act := newTestKeyStore(t, "password")
+	message := []byte("Go TST")
+
+	var signingAccount *account.Account
+	var signature *crypto.ECDSASignature
+	ok := ks.SignWith(act.Address.Encode(testPrefix), "password", func(unlocked *account.Account) bool {
+		signingAccount = unlocked
+		signature, _ = crypto.SignByteArray(message, unlocked.PrivateKey)
+		return signature != nil
+	})
+	if !ok {
+		t.Fatalf(`Failed to sign with keystore account`)
+	}
+
+	if !signingAccount.IsLocked() {
+		t.Fatalf(`Account was left unlocked after signing`)
+	}
+
+	if !crypto.VerifyByteArray(message, act.PublicKey, signature) {
+		t.Fatalf(`Signature made with keystore account did not verify`)
+	}
+}
+
+// Tests that changing the password invalidates the old password
+func TestChangePassword(t *testing.T) {
+	ks, act := newTestKeyStore(t, "old")
+
+	if ks.ChangePassword(act.Address.Encode(testPrefix), "wrong", "new") {
+		t.Fatalf(`Changed password without the correct old password`)
+	}
+
+	if !ks.ChangePassword(act.Address.Encode(testPrefix), "old", "new") {
+		t.Fatalf(`Failed to change password`)
+	}
+
+	if _, ok := ks.Unlock(act.Address.Encode(testPrefix), "old"); ok {
+		t.Fatalf(`Unlocked account with the old password`)
+	}
+	if _, ok := ks.Unlock(act.Address.Encode(testPrefix), "new"); !ok {
+		t.Fatalf(`Failed to unlock account with the new password`)
+	}
+}
+
+// Tests that a key exported from one keystore can be imported into another
+func TestExportImport(t *testing.T) {
+	ks, act := newTestKeyStore(t, "password")
+
+	keyJSON, ok := ks.Export(act.Address.Encode(testPrefix), "password", "transfer")
+	if !ok {
+		t.Fatalf(`Failed to export key`)
+	}
+
+	otherKs, _ := NewWithScryptParams(t.TempDir(), testPrefix, LightScryptN, LightScryptP)
+	if _, ok := otherKs.Import(keyJSON, "password", "imported"); ok {
+		t.Fatalf(`Imported key with the wrong export password`)
+	}
+
+	// A key exported on another network is refused
+	otherNetworkKs, _ := NewWithScryptParams(t.TempDir(), protocol.MainNetParams.AddressPrefix, LightScryptN, LightScryptP)
+	if _, ok := otherNetworkKs.Import(keyJSON, "transfer", "imported"); ok {
+		t.Fatalf(`Imported key from another network`)
+	}
+
+	address, ok := otherKs.Import(keyJSON, "transfer", "imported")
+	if !ok || address != act.Address.Encode(testPrefix) {
+		t.Fatalf(`Failed to import key`)
+	}
+
+	unlocked, ok := otherKs.Unlock(act.Address.Encode(testPrefix), "imported")
+	if !ok || !bytes.Equal(unlocked.PrivateKey, act.PrivateKey) {
+		t.Fatalf(`Imported key does not match the exported key`)
+	}
+}
+
+// Tests that a key file whose envelope was modified cannot be decrypted
+func TestTamperedKeyFile(t *testing.T) {
+	ks, act := newTestKeyStore(t, "password")
+	other, _ := account.New()
+
+	// Swap in another account's public key and address while keeping the ciphertext
+	keyFile, _ := ks.readKeyFile(act.Address.Encode(testPrefix))
+	keyFile.PublicKey = hex.EncodeToString(other.PublicKey)
+	keyFile.Address = other.Address.Encode(testPrefix)
+	if _, ok := ks.decryptKey(keyFile, "password"); ok {
+		t.Fatalf(`Decrypted a key file whose envelope was tampered with`)
+	}
+
+	keyFile, _ = ks.readKeyFile(act.Address.Encode(testPrefix))
+	keyFile.Version = CurrentVersion + 1
+	if _, ok := ks.decryptKey(keyFile, "password"); ok {
+		t.Fatalf(`Decrypted a key file with an unsupported version`)
+	}
+}
+
+// Tests that a key file asking for an excessive scrypt cost is refused before deriving a key
+func TestKeyFileScryptLimits(t *testing.T) {
+	ks, act := newTestKeyStore(t, "password")
+
+	for _, params := range [][3]int{{MaxScryptN * 2, scryptR, 1}, {LightScryptN, scryptR, MaxScryptP + 1}, {LightScryptN, 1 << 20, 1}, {LightScryptN + 1, scryptR, 1}} {
+		keyFile, _ := ks.readKeyFile(act.Address.Encode(testPrefix))
+		keyFile.Crypto.KDFParams.N = params[0]
+		keyFile.Crypto.KDFParams.R = params[1]
+		keyFile.Crypto.KDFParams.P = params[2]
+		if _, ok := ks.decryptKey(keyFile, "password"); ok {
+			t.Fatalf(`Decrypted a key file with scrypt parameters %v`, params)
+		}
+	}
+
+	if _, ok := NewWithScryptParams(t.TempDir(), testPrefix, MaxScryptN*2, 1); ok {
+		t.Fatalf(`Created a keystore with scrypt parameters above the maximum`)
+	}
+}
@@ -0,0 +1,178 @@
+package keystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const walletFileName = "wallet" + keyFileSuffix // The name of the file a wallet's seed is stored in
+
+// A wallet file is the versioned JSON envelope a wallet's seed and mnemonic are stored in
+// The extended public key is kept in the clear so the wallet's addresses can be derived without the password
+type WalletFile struct {
+	Version           int       `json:"version"`
+	ExtendedPublicKey string    `json:"extendedPublicKey"`
+	Crypto            KeyCrypto `json:"crypto"`
+}
+
+// Encrypts a wallet's seed and mnemonic with a password and stores them
+// The mnemonic may be empty if the wallet was not created from one
+// Returns a bool indicating success, which fails if the keystore already holds a wallet
+func (ks *KeyStore) StoreWallet(extendedPublicKey string, mnemonic string, seed []byte, password string) bool {
+	if ks.HasWallet() {
+		log.Warn("Keystore already contains a wallet")
+		return false
+	}
+	if len(seed) == 0 || len(seed) > 255 {
+		log.Warn("Invalid wallet seed", "length", len(seed))
+		return false
+	}
+
+	// The seed is prefixed with its length so the mnemonic can follow it
+	secret := make([]byte, 0, 1+len(seed)+len(mnemonic))
+	secret = append(secret, byte(len(seed)))
+	secret = append(secret, seed...)
+	secret = append(secret, mnemonic...)
+	defer zeroBytes(secret)
+
+	walletFile := WalletFile{
+		Version:           CurrentVersion,
+		ExtendedPublicKey: extendedPublicKey,
+	}
+	keyCrypto, ok := ks.seal(secret, walletFile.additionalData(), password)
+	if !ok {
+		return false
+	}
+	walletFile.Crypto = *keyCrypto
+
+	walletJSON, err := json.MarshalIndent(walletFile, "", "  ")
+	if err != nil {
+		log.Error("Failed to encode wallet file", "err", err)
+		return false
+	}
+
+	// Never replace a stored wallet, since its seed is the only way to recover its accounts
+	file, err := os.OpenFile(ks.walletFilePath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Error("Failed to create wallet file", "err", err)
+		return false
+	}
+	if _, err := file.Write(walletJSON); err != nil {
+		file.Close()
+		os.Remove(ks.walletFilePath())
+		log.Error("Failed to write wallet file", "err", err)
+		return false
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(ks.walletFilePath())
+		log.Error("Failed to write wallet file", "err", err)
+		return false
+	}
+
+	return true
+}
+
+// Returns true if the keystore holds a wallet
+func (ks *KeyStore) HasWallet() bool {
+	_, err := os.Stat(ks.walletFilePath())
+	return err == nil
+}
+
+// Returns the serialized extended public key of the stored wallet, which can derive its addresses without the password
+// Returns a bool indicating success
+func (ks *KeyStore) WalletPublicKey() (string, bool) {
+	walletFile, ok := ks.readWalletFile()
+	if !ok {
+		return "", false
+	}
+
+	return walletFile.ExtendedPublicKey, true
+}
+
+// Decrypts the seed of the stored wallet
+// The caller should overwrite the seed with zeros as soon as it is done signing
+// Returns a bool indicating success, which fails if the password is incorrect
+func (ks *KeyStore) UnlockWallet(password string) ([]byte, bool) {
+	secret, ok := ks.openWallet(password)
+	if !ok {
+		return nil, false
+	}
+	defer zeroBytes(secret)
+
+	seed := make([]byte, secret[0])
+	copy(seed, secret[1:])
+
+	return seed, true
+}
+
+// Decrypts the mnemonic phrase of the stored wallet so it can be written down as a backup
+// Returns a bool indicating success, which fails if the password is incorrect
+func (ks *KeyStore) WalletMnemonic(password string) (string, bool) {
+	secret, ok := ks.openWallet(password)
+	if !ok {
+		return "", false
+	}
+	defer zeroBytes(secret)
+
+	return string(secret[1+int(secret[0]):]), true
+}
+
+// Decrypts the secret in the wallet file, checking that it holds a seed
+func (ks *KeyStore) openWallet(password string) ([]byte, bool) {
+	walletFile, ok := ks.readWalletFile()
+	if !ok {
+		return nil, false
+	}
+	if walletFile.Version != CurrentVersion {
+		log.Warn("Unsupported wallet file version", "version", walletFile.Version)
+		return nil, false
+	}
+
+	secret, ok := open(&walletFile.Crypto, walletFile.additionalData(), password)
+	if !ok {
+		return nil, false
+	}
+	if len(secret) == 0 || secret[0] == 0 || len(secret) < 1+int(secret[0]) {
+		zeroBytes(secret)
+		log.Warn("Malformed wallet file")
+		return nil, false
+	}
+
+	return secret, true
+}
+
+// Returns the data authenticated along with the wallet's secret, binding the ciphertext to its envelope
+func (walletFile *WalletFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("%v:%v", walletFile.Version, walletFile.ExtendedPublicKey))
+}
+
+// Reads the wallet file from the keystore directory
+func (ks *KeyStore) readWalletFile() (*WalletFile, bool) {
+	walletJSON, err := os.ReadFile(ks.walletFilePath())
+	if err != nil {
+		log.Warn("Failed to read wallet file", "err", err)
+		return nil, false
+	}
+
+	walletFile := new(WalletFile)
+	if err := json.Unmarshal(walletJSON, walletFile); err != nil {
+		log.Warn("Malformed wallet file", "err", err)
+		return nil, false
+	}
+
+	return walletFile, true
+}
+
+// Returns the path of the wallet file
+func (ks *KeyStore) walletFilePath() string {
+	return filepath.Join(ks.Directory, walletFileName)
+}
+
+// Overwrites a secret with zeros
+func zeroBytes(secret []byte) {
+	for i := range secret {
+		secret[i] = 0
+	}
+}
@@ -0,0 +1,46 @@
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// Tests that a wallet's seed and mnemonic are stored encrypted and can only be recovered with the password
+func TestStoreWallet(t *testing.T) {
+	ks, _ := NewWithScryptParams(t.TempDir(), testPrefix, LightScryptN, LightScryptP)
+	seed := []byte("testcoin wallet seed for testing")
+	mnemonic := "abandon abandon about"
+
+	if ks.HasWallet() {
+		t.Fatalf(`Empty keystore reports a wallet`)
+	}
+	if !ks.StoreWallet("xpub", mnemonic, seed, "password") {
+		t.Fatalf(`Failed to store wallet`)
+	}
+	if ks.StoreWallet("xpub", mnemonic, seed, "password") {
+		t.Fatalf(`Replaced a stored wallet`)
+	}
+	if len(ks.Addresses()) != 0 {
+		t.Fatalf(`Wallet file is listed as an account`)
+	}
+
+	walletJSON, _ := os.ReadFile(ks.walletFilePath())
+	if bytes.Contains(walletJSON, seed) || bytes.Contains(walletJSON, []byte(mnemonic)) {
+		t.Fatalf(`Wallet file holds the seed or mnemonic in the clear`)
+	}
+
+	if _, ok := ks.UnlockWallet("wrong"); ok {
+		t.Fatalf(`Unlocked wallet with the wrong password`)
+	}
+	unlocked, ok := ks.UnlockWallet("password")
+	if !ok || !bytes.Equal(unlocked, seed) {
+		t.Fatalf(`Unlocked seed does not match the stored seed`)
+	}
+	if stored, ok := ks.WalletMnemonic("password"); !ok || stored != mnemonic {
+		t.Fatalf(`Stored mnemonic does not match, got %q`, stored)
+	}
+	if xpub, _ := ks.WalletPublicKey(); xpub != "xpub" {
+		t.Fatalf(`Stored extended public key does not match, got %q`, xpub)
+	}
+}
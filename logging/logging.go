@@ -0,0 +1,309 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The severity of a log entry
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff // Disables all logging when used as a level threshold
+)
+
+const DefaultLevel = LevelInfo // The level threshold of subsystems that have not been given their own
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+	LevelOff:   "off",
+}
+
+func (l Level) String() string {
+	name, ok := levelNames[l]
+	if !ok {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+
+	return name
+}
+
+// Parses a level name such as "debug" or "WARN"
+// Returns a bool indicating success
+func ParseLevel(s string) (Level, bool) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, true
+		}
+	}
+
+	return 0, false
+}
+
+// The encoding of log entries
+type Format int
+
+const (
+	FormatText Format = iota // time LEVEL [subsystem] message key=value ...
+	FormatJSON               // One JSON object per line
+)
+
+// A backend writes the entries of every logger created from it to a single output
+// Levels are set per subsystem, falling back to the default level
+type Backend struct {
+	mu           sync.Mutex
+	out          io.Writer
+	format       Format
+	defaultLevel Level
+	levels       map[string]Level
+	now          func() time.Time
+}
+
+// Creates a backend writing text entries at the default level to out
+func NewBackend(out io.Writer) *Backend {
+	backend := Backend{
+		out:          out,
+		format:       FormatText,
+		defaultLevel: DefaultLevel,
+		levels:       make(map[string]Level),
+		now:          time.Now,
+	}
+
+	return &backend
+}
+
+// Returns a logger whose entries are tagged with the given subsystem
+func (b *Backend) Logger(subsystem string) *Logger {
+	return &Logger{backend: b, subsystem: subsystem}
+}
+
+// Sets where entries are written, such as os.Stdout or a rotating file
+func (b *Backend) SetOutput(out io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.out = out
+}
+
+// Sets the encoding of entries
+func (b *Backend) SetFormat(format Format) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.format = format
+}
+
+// Sets the level threshold of subsystems that have not been given their own
+func (b *Backend) SetDefaultLevel(level Level) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.defaultLevel = level
+}
+
+// Sets the level threshold of a subsystem, below which its entries are dropped
+func (b *Backend) SetLevel(subsystem string, level Level) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.levels[subsystem] = level
+}
+
+// Returns the level threshold of a subsystem
+func (b *Backend) Level(subsystem string) Level {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.level(subsystem)
+}
+
+func (b *Backend) level(subsystem string) Level {
+	if level, ok := b.levels[subsystem]; ok {
+		return level
+	}
+
+	return b.defaultLevel
+}
+
+// Encodes and writes a single entry if its level passes the subsystem's threshold
+func (b *Backend) write(level Level, subsystem string, msg string, keyvals []interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if level < b.level(subsystem) || b.out == nil {
+		return
+	}
+
+	keyvals = normalizeKeyvals(keyvals)
+	var line string
+	switch b.format {
+	case FormatJSON:
+		line = encodeJSON(b.now(), level, subsystem, msg, keyvals)
+	default:
+		line = encodeText(b.now(), level, subsystem, msg, keyvals)
+	}
+
+	io.WriteString(b.out, line)
+}
+
+// A logger writes entries for one subsystem, carrying fields added with With
+type Logger struct {
+	backend   *Backend
+	subsystem string
+	fields    []interface{}
+}
+
+// Returns a logger that adds the given key value pairs to every entry
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+
+	return &Logger{backend: l.backend, subsystem: l.subsystem, fields: fields}
+}
+
+// Returns true if entries at the given level would be written
+// Useful to skip computing expensive fields
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.backend.Level(l.subsystem)
+}
+
+// Logs a message with alternating keys and values, such as "hash", blk.Hash().Hex(), "height", 3
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if len(l.fields) > 0 {
+		keyvals = append(append([]interface{}{}, l.fields...), keyvals...)
+	}
+	l.backend.write(level, l.subsystem, msg, keyvals)
+}
+
+var defaultBackend = NewBackend(os.Stdout)
+
+// Returns the backend used by loggers returned from Get
+func Default() *Backend {
+	return defaultBackend
+}
+
+// Returns a logger for a subsystem of the node, such as "chain" or "miner", writing to the default backend
+func Get(subsystem string) *Logger {
+	return defaultBackend.Logger(subsystem)
+}
+
+// Sets where the default backend writes entries
+func SetOutput(out io.Writer) {
+	defaultBackend.SetOutput(out)
+}
+
+// Sets the encoding of the default backend
+func SetFormat(format Format) {
+	defaultBackend.SetFormat(format)
+}
+
+// Sets the level threshold of subsystems of the default backend that have not been given their own
+func SetDefaultLevel(level Level) {
+	defaultBackend.SetDefaultLevel(level)
+}
+
+// Sets the level threshold of a subsystem of the default backend
+func SetLevel(subsystem string, level Level) {
+	defaultBackend.SetLevel(subsystem, level)
+}
+
+// Pads a trailing key without a value so every key has one
+func normalizeKeyvals(keyvals []interface{}) []interface{} {
+	if len(keyvals)%2 == 1 {
+		keyvals = append(keyvals, "(missing)")
+	}
+
+	return keyvals
+}
+
+// Formats time LEVEL [subsystem] message key=value ..., quoting values that contain spaces
+func encodeText(t time.Time, level Level, subsystem string, msg string, keyvals []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString(t.UTC().Format(time.RFC3339Nano))
+	sb.WriteString(" ")
+	sb.WriteString(strings.ToUpper(level.String()))
+	sb.WriteString(" [")
+	sb.WriteString(subsystem)
+	sb.WriteString("] ")
+	sb.WriteString(msg)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		value := fmt.Sprint(keyvals[i+1])
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(keyvals[i]))
+		sb.WriteString("=")
+		sb.WriteString(value)
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// Formats a JSON object with time, level, subsystem and msg followed by the fields in order
+func encodeJSON(t time.Time, level Level, subsystem string, msg string, keyvals []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString(`{"time":`)
+	sb.WriteString(jsonValue(t.UTC().Format(time.RFC3339Nano)))
+	sb.WriteString(`,"level":`)
+	sb.WriteString(jsonValue(level.String()))
+	sb.WriteString(`,"subsystem":`)
+	sb.WriteString(jsonValue(subsystem))
+	sb.WriteString(`,"msg":`)
+	sb.WriteString(jsonValue(msg))
+
+	for i := 0; i < len(keyvals); i += 2 {
+		sb.WriteString(",")
+		sb.WriteString(jsonValue(fmt.Sprint(keyvals[i])))
+		sb.WriteString(":")
+		sb.WriteString(jsonValue(keyvals[i+1]))
+	}
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// Encodes a field value, using the text of errors and stringers rather than their structure
+func jsonValue(v interface{}) string {
+	switch value := v.(type) {
+	case error:
+		v = value.Error()
+	case fmt.Stringer:
+		v = value.String()
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Creates a backend writing to a buffer with a fixed clock
+func newTestBackend() (*Backend, *bytes.Buffer) {
+	var buf bytes.Buffer
+	backend := NewBackend(&buf)
+	backend.now = func() time.Time {
+		return time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+
+	return backend, &buf
+}
+
+// Tests that entries below a subsystem's level are dropped
+func TestLevels(t *testing.T) {
+	backend, buf := newTestBackend()
+	chainLog := backend.Logger("chain")
+	minerLog := backend.Logger("miner")
+
+	chainLog.Debug("hidden")
+	chainLog.Info("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Fatalf(`Default level did not filter debug entries: %q`, buf.String())
+	}
+
+	buf.Reset()
+	backend.SetLevel("miner", LevelDebug)
+	backend.SetLevel("chain", LevelError)
+	minerLog.Debug("miner debug")
+	chainLog.Warn("chain warn")
+	if !strings.Contains(buf.String(), "miner debug") || strings.Contains(buf.String(), "chain warn") {
+		t.Fatalf(`Subsystem levels were not applied: %q`, buf.String())
+	}
+
+	if level, ok := ParseLevel("WARN"); !ok || level != LevelWarn {
+		t.Fatalf(`Failed to parse level WARN`)
+	}
+	if _, ok := ParseLevel("loud"); ok {
+		t.Fatalf(`Parsed an unknown level`)
+	}
+}
+
+// Tests the text encoding of fields, including those added with With
+func TestTextFormat(t *testing.T) {
+	backend, buf := newTestBackend()
+	log := backend.Logger("node").With("height", 3)
+
+	log.Info("Added block", "hash", "ab12", "err", errors.New("bad thing"), "dangling")
+	expected := `2022-01-02T03:04:05Z INFO [node] Added block height=3 hash=ab12 err="bad thing" dangling=(missing)` + "\n"
+	if buf.String() != expected {
+		t.Fatalf(`Expected %q, got %q`, expected, buf.String())
+	}
+}
+
+// Tests that JSON entries decode with their fields
+func TestJSONFormat(t *testing.T) {
+	backend, buf := newTestBackend()
+	backend.SetFormat(FormatJSON)
+	backend.Logger("miner").Warn("Solved block", "nonce", 42, "err", errors.New("late"))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf(`Failed to decode JSON entry %q: %v`, buf.String(), err)
+	}
+	if entry["level"] != "warn" || entry["subsystem"] != "miner" || entry["msg"] != "Solved block" {
+		t.Fatalf(`Unexpected JSON entry %v`, entry)
+	}
+	if entry["nonce"] != float64(42) || entry["err"] != "late" {
+		t.Fatalf(`Unexpected JSON fields %v`, entry)
+	}
+}
+
+// Tests that a rotating file keeps a bounded number of backups
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatalf(`Failed to create temp dir: %v`, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "node.log")
+	rf, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf(`Failed to open rotating file: %v`, err)
+	}
+	defer rf.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf(`Failed to write to rotating file: %v`, err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for file, contents := range expected {
+		data, err := ioutil.ReadFile(file)
+		if err != nil || string(data) != contents {
+			t.Fatalf(`Expected %v to contain %q, got %q (%v)`, file, contents, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf(`Kept more backups than allowed`)
+	}
+}
+
+// Tests that a rotating file keeps writing to the current file when it cannot be moved aside
+func TestRotatingFileRenameFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatalf(`Failed to create temp dir: %v`, err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A non-empty directory in place of the backup can neither be removed nor replaced
+	path := filepath.Join(dir, "node.log")
+	os.MkdirAll(filepath.Join(path+".1", "blocker"), 0700)
+	rf, err := NewRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatalf(`Failed to open rotating file: %v`, err)
+	}
+	defer rf.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf(`Failed to write to rotating file: %v`, err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil || string(data) != "first\nsecond\nthird\n" {
+		t.Fatalf(`Expected every line in the current file, got %q (%v)`, data, err)
+	}
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	DefaultMaxFileSize = 10 * 1024 * 1024 // The size in bytes at which a log file is rotated
+	DefaultMaxBackups  = 3                // The number of rotated log files kept besides the current one
+)
+
+// A rotating file is a log output that moves the current file aside once it grows past a maximum size
+// Rotated files are named path.1, path.2, ... with path.1 the most recent
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// Opens or creates a log file at path for appending
+// A maxSize of zero or less disables rotation
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rf := RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+
+	return &rf, nil
+}
+
+// Writes p to the current file, rotating first if p would take the file past its maximum size
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	// A failed rotation leaves the current file open, so the entry is still written and rotation is retried next time
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil && rf.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+
+	return n, err
+}
+
+// Closes the current file
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+
+	return err
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+
+	return nil
+}
+
+// Shifts each backup up by one, dropping the oldest, and starts a new current file
+// If the current file cannot be moved aside it is reopened, so the file is only left closed if reopening fails
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	if err := rf.moveAside(); err != nil {
+		if openErr := rf.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+
+	return rf.open()
+}
+
+// Moves the current file to the first backup, or removes it if no backups are kept
+func (rf *RotatingFile) moveAside() error {
+	if rf.maxBackups <= 0 {
+		return os.Remove(rf.path)
+	}
+
+	os.Remove(rf.backupPath(rf.maxBackups))
+	for i := rf.maxBackups - 1; i >= 1; i-- {
+		os.Rename(rf.backupPath(i), rf.backupPath(i+1))
+	}
+
+	return os.Rename(rf.path, rf.backupPath(1))
+}
+
+func (rf *RotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%v.%v", rf.path, i)
+}
@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upper bounds, in seconds, of the buckets of histograms that measure latency
+var DefaultLatencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// A metric can write its current value in the Prometheus text exposition format
+type metric interface {
+	write(w io.Writer, name string)
+	kind() string
+}
+
+type entry struct {
+	help   string
+	metric metric
+}
+
+// A registry holds named metrics and exposes them over HTTP
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// Creates an empty registry
+func NewRegistry() *Registry {
+	registry := Registry{
+		entries: make(map[string]*entry),
+	}
+
+	return &registry
+}
+
+// Registers a metric under name
+// Metrics are meant to be declared once as package level variables, so a duplicate name panics
+func (r *Registry) register(name string, help string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[name]; ok {
+		panic("metric " + name + " is already registered")
+	}
+	r.entries[name] = &entry{help: help, metric: m}
+}
+
+// Creates and registers a counter
+func (r *Registry) NewCounter(name string, help string) *Counter {
+	counter := &Counter{}
+	r.register(name, help, counter)
+
+	return counter
+}
+
+// Creates and registers a gauge
+func (r *Registry) NewGauge(name string, help string) *Gauge {
+	gauge := &Gauge{}
+	r.register(name, help, gauge)
+
+	return gauge
+}
+
+// Creates and registers a histogram with the given ascending bucket upper bounds
+func (r *Registry) NewHistogram(name string, help string, buckets []float64) *Histogram {
+	histogram := &Histogram{
+		buckets: append([]float64{}, buckets...),
+		counts:  make([]uint64, len(buckets)),
+	}
+	r.register(name, help, histogram)
+
+	return histogram
+}
+
+// Writes every metric, sorted by name, in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := []string{}
+	entries := make(map[string]*entry)
+	for name, e := range r.entries {
+		names = append(names, name)
+		entries[name] = e
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		e := entries[name]
+		fmt.Fprintf(bw, "# HELP %v %v\n", name, e.help)
+		fmt.Fprintf(bw, "# TYPE %v %v\n", name, e.metric.kind())
+		e.metric.write(bw, name)
+	}
+
+	return bw.Flush()
+}
+
+// Serves the registry's metrics, so a registry can be mounted at /metrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteText(w)
+}
+
+// A counter is a value that only increases, such as the number of blocks connected
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Adds delta to the counter, ignoring negative values
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value += delta
+}
+
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.value
+}
+
+func (c *Counter) kind() string {
+	return "counter"
+}
+
+func (c *Counter) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%v %v\n", name, formatFloat(c.Value()))
+}
+
+// A gauge is a value that can go up and down, such as the tip height
+type Gauge struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (g *Gauge) Set(value float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value = value
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value += delta
+}
+
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.value
+}
+
+func (g *Gauge) kind() string {
+	return "gauge"
+}
+
+func (g *Gauge) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%v %v\n", name, formatFloat(g.Value()))
+}
+
+// A histogram counts observations, such as validation latencies, in buckets of increasing size
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64 // Upper bound of each bucket, in ascending order
+	counts  []uint64  // Number of observations in each bucket, not cumulative
+	count   uint64
+	sum     float64
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i] += 1
+			break
+		}
+	}
+	h.count += 1
+	h.sum += value
+}
+
+// Observes the time elapsed since start, in seconds
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Returns the number of observations and their sum
+func (h *Histogram) Totals() (count uint64, sum float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.count, h.sum
+}
+
+func (h *Histogram) kind() string {
+	return "histogram"
+}
+
+func (h *Histogram) write(w io.Writer, name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var cumulative uint64 = 0
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%v_bucket{le=\"%v\"} %v\n", name, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%v_bucket{le=\"+Inf\"} %v\n", name, h.count)
+	fmt.Fprintf(w, "%v_sum %v\n", name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%v_count %v\n", name, h.count)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var defaultRegistry = NewRegistry()
+
+// Returns the registry that the node's subsystems register their metrics with
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Creates a counter in the default registry
+func NewCounter(name string, help string) *Counter {
+	return defaultRegistry.NewCounter(name, help)
+}
+
+// Creates a gauge in the default registry
+func NewGauge(name string, help string) *Gauge {
+	return defaultRegistry.NewGauge(name, help)
+}
+
+// Creates a histogram in the default registry
+func NewHistogram(name string, help string, buckets []float64) *Histogram {
+	return defaultRegistry.NewHistogram(name, help, buckets)
+}
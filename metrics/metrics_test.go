@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Tests the text exposition of each kind of metric
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("blocks_total", "Blocks connected")
+	gauge := registry.NewGauge("tip_height", "Height of the tip")
+	histogram := registry.NewHistogram("latency_seconds", "Latency", []float64{0.1, 1})
+
+	counter.Inc()
+	counter.Add(2)
+	counter.Add(-5)
+	gauge.Set(7)
+	gauge.Add(-2)
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+
+	var buf bytes.Buffer
+	if err := registry.WriteText(&buf); err != nil {
+		t.Fatalf(`Failed to write metrics: %v`, err)
+	}
+
+	expected := `# HELP blocks_total Blocks connected
+# TYPE blocks_total counter
+blocks_total 3
+# HELP latency_seconds Latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 3.55
+latency_seconds_count 3
+# HELP tip_height Height of the tip
+# TYPE tip_height gauge
+tip_height 5
+`
+	if buf.String() != expected {
+		t.Fatalf(`Expected metrics:
+%v
+got:
+%v`, expected, buf.String())
+	}
+}
+
+// Tests that registering the same name twice panics
+func TestDuplicateMetric(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("blocks_total", "Blocks connected")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf(`Registered a duplicate metric`)
+		}
+	}()
+	registry.NewGauge("blocks_total", "Blocks connected")
+}
+
+// Tests that the registry serves its metrics over HTTP
+func TestServeHTTP(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGauge("mempool_transactions", "Pending transactions").Set(4)
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := ioutil.ReadAll(recorder.Result().Body)
+	if !strings.Contains(string(body), "mempool_transactions 4\n") {
+		t.Fatalf(`Served unexpected metrics: %v`, string(body))
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Fatalf(`Served unexpected content type %v`, contentType)
+	}
+}
@@ -2,7 +2,6 @@ package miner
 
 import (
 	"bytes"
-	"fmt"
 	"math/rand"
 	"sort"
 	"time"
@@ -11,12 +10,23 @@ import (
 	"github.com/AndrewCLu/TestcoinNode/chain"
 	"github.com/AndrewCLu/TestcoinNode/common"
 	"github.com/AndrewCLu/TestcoinNode/consensus"
+	"github.com/AndrewCLu/TestcoinNode/logging"
+	"github.com/AndrewCLu/TestcoinNode/metrics"
 	"github.com/AndrewCLu/TestcoinNode/protocol"
 	"github.com/AndrewCLu/TestcoinNode/transaction"
+	"github.com/AndrewCLu/TestcoinNode/util"
 )
 
 const DefaultHashLimit = 10 * 1000 * 1000 // The maximum number of hashes a miner will attempt to solve a block
 
+var log = logging.Get("miner")
+
+var (
+	hashesTotal     = metrics.NewCounter("testcoin_miner_hashes_total", "Block header hashes computed while solving proof of work.")
+	hashesPerSecond = metrics.NewGauge("testcoin_miner_hashes_per_second", "Hash rate of the most recent solve attempt.")
+	blocksMined     = metrics.NewCounter("testcoin_miner_blocks_mined_total", "Blocks the miner has solved.")
+)
+
 // The configuration for a miner
 type MinerConfig struct {
 	HashLimit int
@@ -49,93 +59,115 @@ func New(coinbase common.Address, chain *chain.Chain, consensus consensus.Consen
 }
 
 // Tries to mine a block from pending transactions on the chain
+// Blocks with no pending transactions only contain the coinbase, which lets coinbase outputs mature
 // Returns the block and a boolean indicating success
 // TODO: Miner must validate transactions
 func (miner *Miner) MineBlock() (blk *block.Block, ok bool) {
-	txs, txOk := miner.Chain.GetPendingTransactions(protocol.MaxTransactionsInBlock)
+	txs, txOk := miner.Chain.GetPendingTransactions(miner.Chain.NumPendingTransactions())
 	if !txOk {
-		fmt.Println("Could not get transactions from the current chain")
+		log.Error("Could not get transactions from the current chain")
 		return nil, false
 	}
 
-	// Sort transactions by decreasing fee
-	sort.Slice(txs, func(i, j int) bool {
-		iFee, _ := miner.Chain.GetPendingTransactionFee(txs[i])
-		jFee, _ := miner.Chain.GetPendingTransactionFee(txs[j])
-		return iFee > jFee
+	lastBlockHash, lastBlockNum, lastBlockOk := miner.Chain.GetLastBlockInfo()
+	if !lastBlockOk {
+		log.Error("Could not get last block from current chain")
+		return nil, false
+	}
+	blockNum := lastBlockNum + 1
+
+	// The space left for transactions once the header and coinbase are accounted for
+	// The coinbase has the same size whatever amount it collects
+	placeholderHeader := block.BlockHeader{Timestamp: time.Now().Round(0)}
+	placeholderCoinbase := miner.newCoinbase(0)
+	remainingSize := protocol.MaxBlockSize - len(placeholderHeader.Bytes()) - placeholderCoinbase.Size()
+
+	// Sort transactions by decreasing fee rate
+	feeRates := make(map[*transaction.Transaction]uint64)
+	for _, tx := range txs {
+		feeRates[tx], _ = miner.Chain.GetPendingTransactionFeeRate(tx)
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		return feeRates[txs[i]] > feeRates[txs[j]]
 	})
 
 	// Select transactions that are valid
 	// Takes transactions one at a time and sees if they maintain valid chain state
 	selectedTransactions := []*transaction.Transaction{}
-	tempChain := miner.Chain.UnsafeCopy()
+	var transactionFees uint64 = 0
+	view := miner.Chain.NewView()
 	for _, tx := range txs {
-		// Ensure we never exceed the transaction limit
-		if len(selectedTransactions) > protocol.MaxTransactionsInBlock {
-			break
+		// Skip transactions that do not fit in the rest of the block
+		txSize := tx.Size()
+		if txSize > remainingSize {
+			continue
 		}
 
 		// Check that including this transaction maintains valid state
-		if !miner.Consensus.ValidatePendingTransaction(tempChain, tx) {
+		if err := miner.Consensus.ValidatePendingTransaction(view, tx); err != nil {
+			log.Debug("Skipping invalid transaction", "tx", tx.Hash().Hex(), "err", err)
 			continue
 		}
 
-		// Append transaction fee output to this transaction
-		transactionFee, _ := tempChain.GetPendingTransactionFee(tx)
-		transactionFeeOutput := &transaction.TransactionOutput{
-			ReceiverAddress: miner.Coinbase,
-			Amount:          transactionFee,
+		// The fee is collected by the coinbase, leaving the signed transaction untouched
+		transactionFee, _ := view.GetPendingTransactionFee(tx)
+		fees, feesOk := util.Amount(transactionFees).Add(util.Amount(transactionFee))
+		if !feesOk {
+			continue
 		}
-		tx.Outputs = append(tx.Outputs, transactionFeeOutput)
+		transactionFees = uint64(fees)
 
-		// Update temp chain and selected transactions
-		tempChain.AddTransaction(tx)
+		// Update the view and selected transactions
+		view.AddTransaction(tx)
 		selectedTransactions = append(selectedTransactions, tx)
+		remainingSize -= txSize
 	}
 
-	if len(selectedTransactions) == 0 {
-		fmt.Println("No pending transactions...cannot mine block")
-		return nil, false
-	}
-
-	lastBlockHash, lastBlockNum, lastBlockOk := miner.Chain.GetLastBlockInfo()
-	if !lastBlockOk {
-		fmt.Println("Could not get last block from current chain")
+	coinbaseAmount, coinbaseOk := util.Amount(miner.Chain.Params().ComputeBlockReward(blockNum)).Add(util.Amount(transactionFees))
+	if !coinbaseOk || uint64(coinbaseAmount) > protocol.MaxOutputValue {
+		log.Error("Coinbase amount is out of range", "height", blockNum, "fees", util.Amount(transactionFees))
 		return nil, false
 	}
-	blockNum := lastBlockNum + 1
-
-	coinbaseOutput := &transaction.TransactionOutput{
-		ReceiverAddress: miner.Coinbase,
-		Amount:          protocol.ComputeBlockReward(blockNum),
-	}
-	coinbase, _ := transaction.New(
-		[]*transaction.TransactionInput{},
-		[]*transaction.TransactionOutput{coinbaseOutput},
-	)
+	coinbase := miner.newCoinbase(uint64(coinbaseAmount))
 
 	block, blockOk := block.New(lastBlockHash, blockNum, selectedTransactions, coinbase)
 	if !blockOk {
-		fmt.Println("Failed to create new block")
+		log.Error("Failed to create new block", "height", blockNum)
 		return nil, false
 	}
 
 	// Compute the nonce that solves a block
 	nonce, solveOk := miner.solve(*block.Header)
 	if !solveOk {
-		fmt.Println("Failed to solve block with allotted parameters")
+		log.Warn("Failed to solve block with allotted parameters", "height", blockNum, "hashLimit", miner.Config.HashLimit)
 		return nil, false
 	}
 	block.Header.Nonce = nonce
 
 	blockHash := block.Hash()
 	for _, tx := range block.Body {
-		fmt.Printf("Block %v confirmed transaction %v\n", blockHash.Hex(), tx.Hash().Hex())
+		log.Debug("Block confirmed transaction", "hash", blockHash.Hex(), "tx", tx.Hash().Hex())
 	}
+	blocksMined.Inc()
+	log.Info("Mined block", "hash", blockHash.Hex(), "height", blockNum, "txs", len(block.Body), "fees", util.Amount(transactionFees))
 
 	return block, true
 }
 
+// Creates a coinbase transaction paying amount to the miner
+func (miner *Miner) newCoinbase(amount uint64) *transaction.Transaction {
+	coinbaseOutput := &transaction.TransactionOutput{
+		ReceiverAddress: miner.Coinbase,
+		Amount:          amount,
+	}
+	coinbase, _ := transaction.New(
+		[]*transaction.TransactionInput{},
+		[]*transaction.TransactionOutput{coinbaseOutput},
+	)
+
+	return coinbase
+}
+
 // Given a block header, compute the nonce that results in a hash under the desired target
 // Does not modify the block header passed in
 func (miner *Miner) solve(header block.BlockHeader) (nonce uint32, ok bool) {
@@ -148,18 +180,18 @@ func (miner *Miner) solve(header block.BlockHeader) (nonce uint32, ok bool) {
 	target := header.Target.FullHash()
 
 	t1 := time.Now()
-	fmt.Printf("Solving block with target %v ...\n", target.Hex())
+	log.Debug("Solving block", "target", target.Hex())
 
-	for count := 0; count < miner.Config.HashLimit; count++ {
-		count += 1
+	for count := 1; count <= miner.Config.HashLimit; count++ {
 		header.Nonce = nonce
 		hash := header.Hash()
 
 		if bytes.Compare(hash.Bytes(), target.Bytes()) < 0 {
 			t2 := time.Now()
 			diff := t2.Sub(t1)
+			recordHashRate(count, diff)
 
-			fmt.Printf("Successfully found nonce %v with %v tries in time %v, yielding hash %v\n", nonce, count, diff, hash.Hex())
+			log.Debug("Found nonce", "nonce", nonce, "tries", count, "time", diff, "hash", hash.Hex())
 			ok = true
 			return
 		}
@@ -168,6 +200,15 @@ func (miner *Miner) solve(header block.BlockHeader) (nonce uint32, ok bool) {
 	}
 
 	// Failed to find an appropriate nonce
+	recordHashRate(miner.Config.HashLimit, time.Since(t1))
 	ok = false
 	return
 }
+
+// Records the number of hashes computed by a solve attempt and the rate they were computed at
+func recordHashRate(hashes int, elapsed time.Duration) {
+	hashesTotal.Add(float64(hashes))
+	if elapsed > 0 {
+		hashesPerSecond.Set(float64(hashes) / elapsed.Seconds())
+	}
+}
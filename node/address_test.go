@@ -0,0 +1,35 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that user supplied addresses are refused if they are corrupted or belong to another network
+func TestRejectsInvalidAddresses(t *testing.T) {
+	node, _ := New(&protocol.DevNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+
+	foreign := protocol.MainNetParams.EncodeAddress(satoshi.Address)
+	if node.Initialize(foreign) || node.Initialize("not an address") {
+		t.Fatalf(`Initialized with an invalid coinbase address`)
+	}
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	if node.BeginMiner(foreign) {
+		t.Fatalf(`Started a miner paying another network's address`)
+	}
+
+	encoded := []byte(node.EncodeAddress(alice.Address))
+	encoded[len(encoded)-1] ^= 1
+	for _, receiver := range []string{protocol.MainNetParams.EncodeAddress(alice.Address), string(encoded)} {
+		if node.NewPeerTransaction(satoshi, receiver, util.MustParseAmount("1"), util.MustParseAmount("1"), nil) != nil {
+			t.Fatalf(`Paid invalid address %v`, receiver)
+		}
+	}
+	if node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("1"), util.MustParseAmount("1"), nil) == nil {
+		t.Fatalf(`Failed to pay a valid address`)
+	}
+}
@@ -0,0 +1,157 @@
+package node
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+const BlockFileLengthLength = 4 // The number of bytes used to designate the length of each block in a block file
+
+var ErrBlockFileMalformed = errors.New("block file is malformed")
+
+// Describes how many blocks an import processed and how quickly
+type ImportStats struct {
+	Blocks       int
+	Transactions int // Body transactions and coinbases
+	Bytes        int64
+	Duration     time.Duration
+}
+
+// Returns the number of blocks imported per second
+func (stats ImportStats) BlocksPerSecond() float64 {
+	if stats.Duration <= 0 {
+		return 0
+	}
+
+	return float64(stats.Blocks) / stats.Duration.Seconds()
+}
+
+// Returns the number of transactions imported per second
+func (stats ImportStats) TransactionsPerSecond() float64 {
+	if stats.Duration <= 0 {
+		return 0
+	}
+
+	return float64(stats.Transactions) / stats.Duration.Seconds()
+}
+
+// Writes every block from genesis to tip to a file as a sequence of length prefixed serialized blocks
+// Returns the number of blocks written, or an error if the node is light, a block has been pruned, or the file cannot be written
+// A failed export removes the partial file, so a file that exists always holds the whole chain
+func (node *Node) ExportBlocks(path string) (int, error) {
+	if node.IsLight() {
+		return 0, ErrNoChain
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	numBlocks, err := node.writeBlocks(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	log.Info("Exported blocks", "path", path, "blocks", numBlocks)
+
+	return numBlocks, nil
+}
+
+// Writes every block from genesis to tip to a writer, returning the number of blocks written
+func (node *Node) writeBlocks(w io.Writer) (int, error) {
+	writer := bufio.NewWriter(w)
+	_, lastBlockNum, _ := node.Chain.GetLastBlockInfo()
+	for blockNum := 0; blockNum <= lastBlockNum; blockNum++ {
+		blk, err := node.GetBlock(blockNum)
+		if err != nil {
+			return blockNum, err
+		}
+		blkBytes := blk.Bytes()
+		if _, err := writer.Write(util.Uint32ToBytes(uint32(len(blkBytes)))); err != nil {
+			return blockNum, err
+		}
+		if _, err := writer.Write(blkBytes); err != nil {
+			return blockNum, err
+		}
+	}
+
+	return lastBlockNum + 1, writer.Flush()
+}
+
+// Reads a block file into a fresh node, checking the first block against the network's genesis rules and fully validating every block after it
+// Returns statistics about the import, or an error explaining which block could not be read or was refused
+func (node *Node) ImportBlocks(path string) (ImportStats, error) {
+	stats := ImportStats{}
+	if node.IsLight() {
+		return stats, ErrNoChain
+	}
+	if _, lastBlockNum, _ := node.Chain.GetLastBlockInfo(); lastBlockNum >= 0 {
+		return stats, errors.New("can only import blocks into a fresh node")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	start := time.Now()
+	lengthBytes := make([]byte, BlockFileLengthLength)
+	for blockNum := 0; ; blockNum++ {
+		if _, err := io.ReadFull(reader, lengthBytes); err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, fmt.Errorf("block %v: %w", blockNum, ErrBlockFileMalformed)
+		}
+
+		// A serialized block is its size plus a few length prefixes, so anything much larger is corrupt
+		length := util.BytesToUint32(lengthBytes)
+		if length > 2*protocol.MaxBlockSize {
+			return stats, fmt.Errorf("block %v has length %v: %w", blockNum, length, ErrBlockFileMalformed)
+		}
+		blkBytes := make([]byte, length)
+		if _, err := io.ReadFull(reader, blkBytes); err != nil {
+			return stats, fmt.Errorf("block %v: %w", blockNum, ErrBlockFileMalformed)
+		}
+		blk, ok := block.BytesToBlock(blkBytes)
+		if !ok {
+			return stats, fmt.Errorf("block %v: %w", blockNum, ErrBlockFileMalformed)
+		}
+
+		if blockNum == 0 {
+			if err := ValidateGenesisBlock(node.Params, blk); err != nil {
+				return stats, fmt.Errorf("block 0 is not a genesis block for %v: %w", node.Params.Name, err)
+			}
+			if !node.Chain.Initialize(blk) {
+				return stats, errors.New("failed to initialize chain with block 0")
+			}
+			node.updateMetrics()
+		} else if err := node.SubmitBlock(blk); err != nil {
+			return stats, fmt.Errorf("block %v was refused: %w", blockNum, err)
+		}
+
+		stats.Blocks++
+		stats.Transactions += len(blk.Body) + 1
+		stats.Bytes += int64(BlockFileLengthLength) + int64(length)
+		stats.Duration = time.Since(start)
+	}
+
+	log.Info("Imported blocks", "path", path, "blocks", stats.Blocks, "txs", stats.Transactions,
+		"bytes", stats.Bytes, "duration", stats.Duration, "blocksPerSecond", stats.BlocksPerSecond(),
+		"txsPerSecond", stats.TransactionsPerSecond())
+
+	return stats, nil
+}
@@ -0,0 +1,95 @@
+package node
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/chain"
+	"github.com/AndrewCLu/TestcoinNode/consensus"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that a chain exported to a block file is rebuilt by importing it into a fresh node
+func TestExportImportBlocks(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+	node.MineBlock()
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("0.01"), nil)
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("2"), util.MustParseAmount("0.01"), nil)
+	node.MineBlock()
+
+	dir, _ := ioutil.TempDir("", "blockfile")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "blocks.dat")
+	numBlocks := node.Params.CoinbaseMaturity + 2
+	if exported, err := node.ExportBlocks(path); err != nil || exported != numBlocks {
+		t.Fatalf(`Exported %v blocks with error %v`, exported, err)
+	}
+
+	imported, _ := New(&protocol.TestNetParams)
+	stats, err := imported.ImportBlocks(path)
+	if err != nil {
+		t.Fatalf(`Failed to import blocks: %v`, err)
+	}
+	if stats.Blocks != numBlocks || stats.Transactions != numBlocks+2 || stats.Bytes == 0 {
+		t.Fatalf(`Got unexpected import stats %+v`, stats)
+	}
+	tip, _, _ := node.Chain.GetLastBlockInfo()
+	if importedTip, _, _ := imported.Chain.GetLastBlockInfo(); !importedTip.Equal(tip) {
+		t.Fatalf(`Imported chain has a different tip`)
+	}
+	if imported.Chain.GetAccountValue(alice.Address) != uint64(util.MustParseAmount("5")) {
+		t.Fatalf(`Imported chain has the wrong balance for alice`)
+	}
+	if _, err := imported.ImportBlocks(path); err == nil {
+		t.Fatalf(`Imported blocks into a node that already has a chain`)
+	}
+
+	// A file that does not start with a genesis block for the network is refused
+	blk, _ := node.Chain.GetBlockByNumber(1)
+	blkBytes := blk.Bytes()
+	ioutil.WriteFile(path, append(util.Uint32ToBytes(uint32(len(blkBytes))), blkBytes...), 0600)
+	noGenesis, _ := New(&protocol.TestNetParams)
+	if _, err := noGenesis.ImportBlocks(path); !errors.Is(err, consensus.ErrBadPrevHash) {
+		t.Fatalf(`Expected a bad genesis previous hash, got %v`, err)
+	}
+	if _, lastBlockNum, _ := noGenesis.Chain.GetLastBlockInfo(); lastBlockNum >= 0 {
+		t.Fatalf(`Initialized a chain from a block that is not a genesis block`)
+	}
+
+	// A genesis coinbase claiming more than the genesis reward is refused
+	genesis := GetGenesisBlock(node.Params, satoshi.Address)
+	genesis.Coinbase.Outputs[0].Amount++
+	genesis.Header.AllTransactionsHash = block.ComputeAllTransactionsHash(genesis.Header.ProtocolVersion, genesis.Body, genesis.Coinbase)
+	if err := ValidateGenesisBlock(node.Params, genesis); !errors.Is(err, consensus.ErrCoinbaseTooLarge) {
+		t.Fatalf(`Expected a genesis coinbase that is too large, got %v`, err)
+	}
+
+	// A truncated file is refused
+	node.ExportBlocks(path)
+	data, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path, data[:len(data)-1], 0600)
+	truncated, _ := New(&protocol.TestNetParams)
+	if _, err := truncated.ImportBlocks(path); !errors.Is(err, ErrBlockFileMalformed) {
+		t.Fatalf(`Expected a malformed file error, got %v`, err)
+	}
+
+	// An export that fails partway does not leave a partial file behind
+	node.EnablePruning(chain.PruneConfig{Depth: chain.MinPruneDepth})
+	prunedPath := filepath.Join(dir, "pruned.dat")
+	if _, err := node.ExportBlocks(prunedPath); !errors.Is(err, ErrBlockPruned) {
+		t.Fatalf(`Expected a pruned block error, got %v`, err)
+	}
+	if _, err := os.Stat(prunedPath); !os.IsNotExist(err) {
+		t.Fatalf(`Failed export left a partial file`)
+	}
+}
@@ -0,0 +1,300 @@
+package node
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+	"github.com/AndrewCLu/TestcoinNode/util"
+	"github.com/AndrewCLu/TestcoinNode/wallet"
+)
+
+// Tests that the node learns fee rates from confirmed transactions and uses them to pay fees automatically
+func TestNewPeerTransactionWithFeeTarget(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+
+	if tx := node.NewPeerTransactionWithFeeTarget(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("1"), 1, nil); tx != nil {
+		t.Fatalf(`Created a transaction without any fee data`)
+	}
+
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("1"), nil)
+	node.MineBlock()
+
+	feeRate, ok := node.EstimateFeeRate(1)
+	if !ok || feeRate == 0 {
+		t.Fatalf(`Failed to estimate a fee rate after a transaction was confirmed`)
+	}
+
+	tx := node.NewPeerTransactionWithFeeTarget(alice, node.EncodeAddress(satoshi.Address), util.MustParseAmount("1"), 1, nil)
+	if tx == nil {
+		t.Fatalf(`Failed to create a transaction with an estimated fee`)
+	}
+
+	fee, _ := node.Chain.GetPendingTransactionFee(tx)
+	if fee < wallet.FeeForSize(feeRate, tx.Size()) {
+		t.Fatalf(`Transaction fee %v does not pay fee rate %v for size %v`, fee, feeRate, tx.Size())
+	}
+}
+
+// Tests that transactions paying less than the minimum relay fee rate are not added to the pending pool
+func TestMinRelayFeeRate(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+
+	rejected := transactionsRejected.Value()
+	if tx := node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("1"), util.MustParseAmount("0"), nil); tx != nil {
+		t.Fatalf(`Returned a transaction the pending pool refused`)
+	}
+	if node.Chain.NumPendingTransactions() != 0 || transactionsRejected.Value() != rejected+1 {
+		t.Fatalf(`Added a transaction paying no fee to the pending pool`)
+	}
+
+	tx := node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("1"), util.MustParseAmount("0.001"), nil)
+	if tx == nil || node.Chain.NumPendingTransactions() != 1 {
+		t.Fatalf(`Failed to add a transaction paying enough fee to the pending pool`)
+	}
+}
+
+// Tests that transactions too large to ever fit in a block are not added to the pending pool
+func TestTransactionTooLarge(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+
+	utxos, _ := node.Chain.GetUnspentTransactions(satoshi.Address)
+	verification := &transaction.TransactionInputVerification{
+		Signature:        node.Consensus.SignInput(satoshi.PrivateKey, utxos[0]),
+		EncodedPublicKey: satoshi.PublicKey,
+	}
+	input := &transaction.TransactionInput{
+		OutputPointer:      utxos[0],
+		VerificationLength: uint16(len(verification.Bytes())),
+		Verification:       verification,
+	}
+	outputs := []*transaction.TransactionOutput{}
+	for len(outputs)*(common.AddressLength+8) < protocol.MaxBlockSize {
+		outputs = append(outputs, &transaction.TransactionOutput{ReceiverAddress: alice.Address, Amount: uint64(util.MustParseAmount("0.01"))})
+	}
+	tx, _ := transaction.New([]*transaction.TransactionInput{input}, outputs)
+
+	if err := node.AddPendingTransaction(tx); !errors.Is(err, ErrTxTooLarge) {
+		t.Fatalf(`Expected a transaction too large error, got %v`, err)
+	}
+	if node.Chain.NumPendingTransactions() != 0 {
+		t.Fatalf(`Added a transaction larger than a block to the pending pool`)
+	}
+}
+
+// Tests that the miner fills blocks with the highest fee rate transactions up to the block size limit
+func TestMineBlockBySize(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+
+	// Split the genesis reward into several outputs so transactions do not spend the same output
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("2"), util.MustParseAmount("0.1"), nil)
+	node.MineBlock()
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("2"), util.MustParseAmount("0.1"), nil)
+	node.MineBlock()
+
+	lowFee := node.NewPeerTransaction(alice, node.EncodeAddress(satoshi.Address), util.MustParseAmount("0.5"), util.MustParseAmount("0.01"), nil)
+	highFee := node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("0.5"), util.MustParseAmount("0.5"), nil)
+	if lowFee == nil || highFee == nil {
+		t.Fatalf(`Failed to create transactions`)
+	}
+
+	for node.Chain.NumPendingTransactions() > 0 {
+		_, lastBlockNum, _ := node.Chain.GetLastBlockInfo()
+		node.MineBlock()
+		blk, _ := node.Chain.GetBlockByNumber(lastBlockNum + 1)
+		if blk == nil {
+			t.Fatalf(`Failed to mine a block`)
+		}
+		if blk.Size() > protocol.MaxBlockSize {
+			t.Fatalf(`Mined block of size %v exceeds the limit of %v`, blk.Size(), protocol.MaxBlockSize)
+		}
+	}
+
+	// The high fee rate transaction is confirmed no later than the low fee rate one
+	_, lastBlockNum, _ := node.Chain.GetLastBlockInfo()
+	highBlock, lowBlock := -1, -1
+	for blockNum := 0; blockNum <= lastBlockNum; blockNum++ {
+		blk, _ := node.Chain.GetBlockByNumber(blockNum)
+		for _, tx := range blk.Body {
+			if tx == highFee {
+				highBlock = blockNum
+			}
+			if tx == lowFee {
+				lowBlock = blockNum
+			}
+		}
+	}
+	if highBlock == -1 || lowBlock == -1 || highBlock > lowBlock {
+		t.Fatalf(`High fee rate transaction confirmed in block %v after low fee rate transaction in block %v`, highBlock, lowBlock)
+	}
+}
+
+// Tests that mining leaves user transactions untouched and pays their fees through the coinbase
+func TestCoinbaseCollectsFees(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	miner := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(miner.Address))
+	mineToMaturity(t, node)
+
+	before := node.Chain.GetAccountValue(miner.Address)
+	tx := node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("0.5"), nil)
+	txHash := tx.Hash()
+	numOutputs := len(tx.Outputs)
+	node.MineBlock()
+
+	if len(tx.Outputs) != numOutputs || !tx.Hash().Equal(txHash) {
+		t.Fatalf(`Mining modified a user transaction`)
+	}
+	if confirmed, _ := node.Chain.GetTransaction(txHash); confirmed == nil {
+		t.Fatalf(`Transaction was not confirmed under its original hash`)
+	}
+
+	_, lastBlockNum, _ := node.Chain.GetLastBlockInfo()
+	expected := node.Params.ComputeBlockReward(lastBlockNum) + uint64(util.MustParseAmount("0.5"))
+	if value := node.Chain.GetAccountValue(miner.Address) - before; value != expected {
+		t.Fatalf(`Miner received %v, expected block reward plus fee of %v`, value, expected)
+	}
+}
+
+// Tests that coin selection counts the fee each input costs, so strategies skip coins that are not worth spending
+func TestCoinSelectionCountsInputCost(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	bob := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+
+	// Alice holds a coin worth less than the fee to spend it, a small coin and a large coin
+	for _, amount := range []string{"0.0005", "0.002", "1"} {
+		node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount(amount), util.MustParseAmount("0.01"), nil)
+		node.MineBlock()
+	}
+
+	spentAmounts := func(tx *transaction.Transaction) []util.Amount {
+		if tx == nil {
+			t.Fatalf(`Failed to create transaction`)
+		}
+		amounts := []util.Amount{}
+		for _, input := range tx.Inputs {
+			amount, _ := node.Chain.GetOutputAmount(input.OutputPointer)
+			amounts = append(amounts, util.Amount(amount))
+		}
+		return amounts
+	}
+	send := func(feeRate uint64, selector wallet.CoinSelector) []util.Amount {
+		return spentAmounts(node.createPeerTransaction(alice, bob.Address, util.MustParseAmount("0.5"), util.MustParseAmount("0.001"), feeRate, selector))
+	}
+	feeRate := uint64(util.MustParseAmount("0.001")) / uint64(wallet.EstimatedSize(1, 2))
+
+	if spent := send(feeRate, &wallet.LargestFirst{}); len(spent) != 1 || spent[0] != util.MustParseAmount("1") {
+		t.Fatalf(`Expected largest first to spend only the large coin, spent %v`, spent)
+	}
+	if spent := send(feeRate, &wallet.SmallestFirst{}); len(spent) != 2 || spent[0] != util.MustParseAmount("0.002") || spent[1] != util.MustParseAmount("1") {
+		t.Fatalf(`Expected smallest first to skip the coin not worth spending, spent %v`, spent)
+	}
+
+	// Without input costs, smallest first would also spend the coin worth less than its fee
+	if spent := send(0, &wallet.SmallestFirst{}); len(spent) != 3 {
+		t.Fatalf(`Expected smallest first to spend every coin when inputs are free, spent %v`, spent)
+	}
+}
+
+// Tests that the pending pool, block submission and fee estimates can run concurrently
+// Run with -race to detect unsynchronized access to the fee estimator
+func TestConcurrentFeeEstimation(t *testing.T) {
+	const numBlocks = 20
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Mine blocks, which confirms and drops tracked transactions
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < numBlocks; i++ {
+			if err := node.MineBlock(); err != nil {
+				t.Errorf(`Failed to mine block %v: %v`, i, err)
+				return
+			}
+		}
+	}()
+
+	// Send until all blocks are mined, some of which are refused for spending coins already pending
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("0.1"), util.MustParseAmount("0.001"), nil)
+		}
+	}()
+
+	// Estimate fee rates until all blocks are mined
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			node.EstimateFeeRate(1)
+		}
+	}()
+
+	wg.Wait()
+
+	// Once the pool drains, every tracked transaction has been confirmed or dropped
+	for i := 0; node.Chain.NumPendingTransactions() > 0; i++ {
+		if i == numBlocks {
+			t.Fatalf(`Failed to drain the pending pool`)
+		}
+		if err := node.MineBlock(); err != nil {
+			t.Fatalf(`Failed to mine block: %v`, err)
+		}
+	}
+	if tracked := node.FeeEstimator.NumTracked(); tracked != 0 {
+		t.Fatalf(`Expected no tracked transactions after the pool drained, got %v`, tracked)
+	}
+	if value := node.GetReadableAccountValue(alice); value == 0 {
+		t.Fatalf(`Expected some sends to be confirmed`)
+	}
+}
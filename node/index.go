@@ -0,0 +1,60 @@
+package node
+
+import (
+	"errors"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/chain"
+	"github.com/AndrewCLu/TestcoinNode/common"
+)
+
+// Enables the transaction and address indexes, building them from the blocks already in the chain
+// Returns a bool indicating success, which fails for light nodes or if blocks have already been pruned
+func (node *Node) EnableIndexes() bool {
+	if node.IsLight() {
+		return false
+	}
+
+	return node.Chain.EnableTransactionIndex() && node.Chain.EnableAddressIndex()
+}
+
+// Gets the block and position that confirmed a transaction
+// Returns bool indicating success, which fails for light nodes, if indexes are disabled, or if the transaction is not confirmed
+func (node *Node) GetTransactionLocation(hash common.Hash) (chain.TransactionLocation, bool) {
+	if node.IsLight() {
+		return chain.TransactionLocation{}, false
+	}
+
+	return node.Chain.GetTransactionLocation(hash)
+}
+
+// Gets the confirmed transactions that spent from or paid to a human readable address, oldest first
+// Returns bool indicating success, which fails for light nodes, if indexes are disabled, or if the address is invalid
+func (node *Node) GetAddressHistory(encoded string) ([]chain.AddressTransaction, bool) {
+	if node.IsLight() {
+		return nil, false
+	}
+	address, ok := node.ParseAddress(encoded)
+	if !ok {
+		return nil, false
+	}
+
+	return node.Chain.GetAddressHistory(address)
+}
+
+// Removes the tip of the chain, returning its transactions to the pending pool
+// Returns the removed block, or an error if there is no block to remove
+func (node *Node) DisconnectTip() (*block.Block, error) {
+	if node.IsLight() {
+		return nil, ErrNoChain
+	}
+
+	blk, ok := node.Chain.DisconnectTip()
+	if !ok {
+		return nil, errors.New("cannot disconnect the genesis block")
+	}
+	node.RemoveInvalidPendingTransactions()
+	node.updateMetrics()
+
+	return blk, nil
+}
@@ -0,0 +1,56 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/keystore"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that storing the wallet erases its secrets, and that its accounts are only unlocked with the password while signing
+func TestStoreWallet(t *testing.T) {
+	node, _ := New(&protocol.DevNetParams)
+	satoshi := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	node.MineBlock()
+
+	directory := t.TempDir()
+	node.KeyStore, _ = keystore.NewWithScryptParams(directory, node.Params.AddressPrefix, keystore.LightScryptN, keystore.LightScryptP)
+	alice := node.NewStoredAccount("password")
+	if alice == nil || !alice.IsLocked() {
+		t.Fatalf(`Failed to create a locked stored account`)
+	}
+	if node.Wallet.Seed != nil || node.Wallet.Mnemonic != "" || !node.Wallet.IsWatchOnly() || !satoshi.IsLocked() {
+		t.Fatalf(`Wallet still holds secrets after it was stored`)
+	}
+
+	// Accounts derived by the wallet are signed for with the seed in the keystore
+	if node.NewPeerTransactionFromKeyStore(node.EncodeAddress(satoshi.Address), "wrong", node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("1"), nil) != nil {
+		t.Fatalf(`Signed with the wrong password`)
+	}
+	if node.NewPeerTransactionFromKeyStore(node.EncodeAddress(satoshi.Address), "password", node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("1"), nil) == nil {
+		t.Fatalf(`Failed to sign with an account of the stored wallet`)
+	}
+	node.MineBlock()
+	if node.NewPeerTransactionFromKeyStore(node.EncodeAddress(alice.Address), "password", node.EncodeAddress(satoshi.Address), util.MustParseAmount("1"), util.MustParseAmount("1"), nil) == nil {
+		t.Fatalf(`Failed to sign with a stored account`)
+	}
+	if !satoshi.IsLocked() || !alice.IsLocked() {
+		t.Fatalf(`Accounts were left unlocked after signing`)
+	}
+
+	// Another node can load the wallet and derive the same accounts
+	other, _ := New(&protocol.DevNetParams)
+	other.KeyStore, _ = keystore.NewWithScryptParams(directory, other.Params.AddressPrefix, keystore.LightScryptN, keystore.LightScryptP)
+	if other.LoadWallet("wrong") {
+		t.Fatalf(`Loaded the wallet with the wrong password`)
+	}
+	if !other.LoadWallet("password") {
+		t.Fatalf(`Failed to load the stored wallet`)
+	}
+	if act := other.NewAccount(); act == nil || !act.Address.Equal(satoshi.Address) || !act.IsLocked() {
+		t.Fatalf(`Loaded wallet does not derive the same locked accounts`)
+	}
+}
@@ -0,0 +1,211 @@
+package node
+
+import (
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/chain"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/consensus/pow"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// A transaction proof shows that a transaction is included in the block with the given hash
+type TransactionProof struct {
+	Transaction *transaction.Transaction `json:"transaction"`
+	BlockHash   common.Hash              `json:"blockHash"`
+	Proof       *block.MerkleProof       `json:"proof"`
+}
+
+// A full node peer serves the block headers and transaction proofs needed by light nodes
+type FullNodePeer interface {
+	// Gets the headers of all blocks starting at the given block number
+	GetBlockHeaders(startNum int) ([]*block.BlockHeader, bool)
+
+	// Gets proofs for all confirmed transactions sending to or from the given address
+	GetTransactionProofs(address common.Address) ([]*TransactionProof, bool)
+}
+
+// Creates a light node, which stores only block headers and the transactions relevant to its accounts
+// The light node trusts the given genesis header
+func NewLight(params *protocol.Params, genesisHeader *block.BlockHeader) (n *Node, ok bool) {
+	headers, _ := chain.NewHeaderChain()
+	headers.Initialize(genesisHeader)
+	pow, _ := pow.New()
+	node := Node{
+		Params:    params,
+		Headers:   headers,
+		Consensus: pow,
+	}
+
+	return &node, true
+}
+
+// Returns true if the node is a light node
+func (node *Node) IsLight() bool {
+	return node.Chain == nil
+}
+
+// Gets the headers of all blocks starting at the given block number
+// Returns bool indicating success, which fails for light nodes
+func (node *Node) GetBlockHeaders(startNum int) ([]*block.BlockHeader, bool) {
+	if node.IsLight() {
+		return nil, false
+	}
+
+	return node.Chain.GetBlockHeaders(startNum)
+}
+
+// Gets proofs for all confirmed transactions sending to or from the given address
+// Returns bool indicating success, which fails for light nodes and for pruned chains, which cannot prove transactions in pruned blocks
+func (node *Node) GetTransactionProofs(address common.Address) ([]*TransactionProof, bool) {
+	if node.IsLight() {
+		return nil, false
+	}
+	if pruneHeight := node.Chain.PruneHeight(); pruneHeight > 0 {
+		log.Warn("Cannot prove transactions in pruned blocks", "pruneHeight", pruneHeight)
+		return nil, false
+	}
+
+	proofs := []*TransactionProof{}
+	for blockNum := 0; ; blockNum++ {
+		blk, ok := node.Chain.GetBlockByNumber(blockNum)
+		if !ok {
+			break
+		}
+
+		blockHash := blk.Hash()
+		blockTransactions := append([]*transaction.Transaction{blk.Coinbase}, blk.Body...)
+		for _, tx := range blockTransactions {
+			if !node.isTransactionRelevant(tx, address) {
+				continue
+			}
+
+			proof, proofOk := blk.NewMerkleProof(tx.Hash())
+			if !proofOk {
+				continue
+			}
+
+			proofs = append(proofs, &TransactionProof{
+				Transaction: tx,
+				BlockHash:   blockHash,
+				Proof:       proof,
+			})
+		}
+	}
+
+	return proofs, true
+}
+
+// Downloads new block headers from a full node and adds them to the header chain if valid
+// Returns a bool indicating success
+func (node *Node) SyncHeaders(peer FullNodePeer) bool {
+	if !node.IsLight() {
+		return false
+	}
+
+	lastHash, lastBlockNum, lastOk := node.Headers.GetLastHeaderInfo()
+	if !lastOk {
+		log.Error("Could not get last header from header chain")
+		return false
+	}
+
+	headers, ok := peer.GetBlockHeaders(lastBlockNum + 1)
+	if !ok {
+		log.Warn("Could not get block headers from peer", "from", lastBlockNum+1)
+		return false
+	}
+
+	for _, header := range headers {
+		if err := node.Consensus.ValidateBlockHeader(lastHash, lastBlockNum+1, header); err != nil {
+			log.Warn("Received invalid header, stopping sync", "hash", header.Hash().Hex(), "height", lastBlockNum+1, "err", err)
+			return false
+		}
+
+		node.Headers.AddHeader(header)
+		lastHash = header.Hash()
+		lastBlockNum += 1
+	}
+	log.Info("Synced headers", "hash", lastHash.Hex(), "height", lastBlockNum)
+	node.updateMetrics()
+
+	return true
+}
+
+// Downloads the transactions of a human readable address from a full node and stores those with a valid inclusion proof
+// Headers should be synced first, since proofs are checked against known headers
+// Returns a bool indicating success
+func (node *Node) SyncAddress(peer FullNodePeer, encoded string) bool {
+	if !node.IsLight() {
+		return false
+	}
+	address, ok := node.ParseAddress(encoded)
+	if !ok {
+		return false
+	}
+
+	proofs, ok := peer.GetTransactionProofs(address)
+	if !ok {
+		log.Warn("Could not get transaction proofs from peer", "address", encoded)
+		return false
+	}
+
+	for i, txProof := range proofs {
+		if !node.VerifyTransactionProof(txProof) {
+			log.Warn("Received invalid transaction proof, stopping sync", "address", encoded, "index", i)
+			return false
+		}
+
+		node.Headers.AddVerifiedTransaction(txProof.Transaction)
+	}
+
+	return true
+}
+
+// Returns true if the transaction is proven to be included in a block of the light node's header chain
+// Proofs come from untrusted peers, so missing fields fail verification
+func (node *Node) VerifyTransactionProof(txProof *TransactionProof) bool {
+	if txProof == nil || txProof.Transaction == nil {
+		return false
+	}
+	header, ok := node.Headers.GetHeader(txProof.BlockHash)
+	if !ok {
+		return false
+	}
+
+	return block.VerifyTransactionInclusion(header, txProof.Transaction.Hash(), txProof.Proof)
+}
+
+// Gets the value of a human readable address based on the transactions verified by a light node
+func (node *Node) GetReadableLightAccountValue(encoded string) util.Amount {
+	address, ok := node.ParseAddress(encoded)
+	if !ok {
+		return 0
+	}
+	total := util.Amount(node.Headers.GetAccountValue(address))
+
+	log.Debug("Read light account value", "address", encoded, "value", total)
+
+	return total
+}
+
+// Returns true if a confirmed transaction sends to or spends from the given address
+func (node *Node) isTransactionRelevant(tx *transaction.Transaction, address common.Address) bool {
+	for _, output := range tx.Outputs {
+		if output.ReceiverAddress.Equal(address) {
+			return true
+		}
+	}
+
+	for _, input := range tx.Inputs {
+		outputTx, ok := node.Chain.GetTransaction(input.OutputPointer.TransactionHash)
+		if !ok || outputTx == nil {
+			continue
+		}
+		if outputTx.Outputs[input.OutputPointer.OutputIndex].ReceiverAddress.Equal(address) {
+			return true
+		}
+	}
+
+	return false
+}
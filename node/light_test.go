@@ -0,0 +1,138 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that a light node syncs headers from a full node and computes account values from verified transactions
+func TestLightNodeSync(t *testing.T) {
+	fullNode, _ := New(&protocol.TestNetParams)
+	satoshi := fullNode.NewAccount()
+	alice := fullNode.NewAccount()
+	fullNode.Initialize(fullNode.EncodeAddress(satoshi.Address))
+	fullNode.BeginMiner(fullNode.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, fullNode)
+
+	fullNode.NewPeerTransaction(satoshi, fullNode.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("1"), nil)
+	fullNode.MineBlock()
+	fullNode.NewPeerTransaction(alice, fullNode.EncodeAddress(satoshi.Address), util.MustParseAmount("1"), util.MustParseAmount("0.5"), nil)
+	fullNode.MineBlock()
+
+	genesisHeader, _ := fullNode.Chain.GetBlockHeaders(0)
+	lightNode, _ := NewLight(&protocol.TestNetParams, genesisHeader[0])
+
+	if !lightNode.SyncHeaders(fullNode) {
+		t.Fatalf(`Light node failed to sync headers`)
+	}
+
+	fullHash, fullBlockNum, _ := fullNode.Chain.GetLastBlockInfo()
+	lightHash, lightBlockNum, _ := lightNode.Headers.GetLastHeaderInfo()
+	if !fullHash.Equal(lightHash) || fullBlockNum != lightBlockNum {
+		t.Fatalf(`Light node tip does not match full node. Full: %v, Light: %v`, fullBlockNum, lightBlockNum)
+	}
+
+	for _, address := range []common.Address{satoshi.Address, alice.Address} {
+		if !lightNode.SyncAddress(fullNode, lightNode.EncodeAddress(address)) {
+			t.Fatalf(`Light node failed to sync address %x`, address)
+		}
+	}
+
+	if lightNode.Headers.GetAccountValue(alice.Address) != fullNode.Chain.GetAccountValue(alice.Address) {
+		t.Fatalf(`Light node value for alice does not match full node`)
+	}
+	if lightNode.Headers.GetAccountValue(satoshi.Address) != fullNode.Chain.GetAccountValue(satoshi.Address) {
+		t.Fatalf(`Light node value for satoshi does not match full node`)
+	}
+}
+
+// Tests that a light node rejects transaction proofs that do not match its headers
+func TestLightNodeRejectsInvalidProof(t *testing.T) {
+	fullNode, _ := New(&protocol.TestNetParams)
+	satoshi := fullNode.NewAccount()
+	fullNode.Initialize(fullNode.EncodeAddress(satoshi.Address))
+
+	headers, _ := fullNode.Chain.GetBlockHeaders(0)
+	lightNode, _ := NewLight(&protocol.TestNetParams, headers[0])
+
+	proofs, _ := fullNode.GetTransactionProofs(satoshi.Address)
+	if len(proofs) != 1 {
+		t.Fatalf(`Expected one proof for the genesis coinbase, got %v`, len(proofs))
+	}
+
+	if !lightNode.VerifyTransactionProof(proofs[0]) {
+		t.Fatalf(`Failed to verify valid proof for the genesis coinbase`)
+	}
+
+	// Tamper with a copy so the full node's genesis coinbase is left untouched
+	tampered := *proofs[0]
+	tampered.Transaction = transaction.BytesToTransaction(proofs[0].Transaction.Bytes())
+	tampered.Transaction.Outputs[0].Amount += 1
+	if lightNode.VerifyTransactionProof(&tampered) {
+		t.Fatalf(`Verified proof for a transaction that was tampered with`)
+	}
+}
+
+// Tests that operations needing the full ledger or a wallet fail on a light node instead of panicking
+func TestLightNodeRefusesFullNodeOperations(t *testing.T) {
+	fullNode, _ := New(&protocol.TestNetParams)
+	satoshi := fullNode.NewAccount()
+	fullNode.Initialize(fullNode.EncodeAddress(satoshi.Address))
+	headers, _ := fullNode.Chain.GetBlockHeaders(0)
+	lightNode, _ := NewLight(&protocol.TestNetParams, headers[0])
+
+	if lightNode.Initialize(lightNode.EncodeAddress(satoshi.Address)) {
+		t.Fatalf(`Initialized a light node with a genesis block`)
+	}
+	if lightNode.NewAccount() != nil {
+		t.Fatalf(`Created an account on a light node without a wallet`)
+	}
+	if _, ok := lightNode.RestoreWallet(fullNode.Wallet.Mnemonic, "", 5); ok {
+		t.Fatalf(`Restored a wallet on a light node`)
+	}
+	if lightNode.NewPeerTransaction(satoshi, lightNode.EncodeAddress(satoshi.Address), util.MustParseAmount("1"), util.MustParseAmount("1"), nil) != nil {
+		t.Fatalf(`Created a peer transaction on a light node`)
+	}
+	if lightNode.NewPeerTransactionWithFeeTarget(satoshi, lightNode.EncodeAddress(satoshi.Address), util.MustParseAmount("1"), 1, nil) != nil {
+		t.Fatalf(`Created a fee targeted peer transaction on a light node`)
+	}
+	if lightNode.GetReadableAccountValue(satoshi) != 0 {
+		t.Fatalf(`Computed an account value on a light node`)
+	}
+}
+
+// A full node peer that serves malformed transaction proofs
+type malformedPeer struct {
+	*Node
+	proofs []*TransactionProof
+}
+
+func (peer *malformedPeer) GetTransactionProofs(address common.Address) ([]*TransactionProof, bool) {
+	return peer.proofs, true
+}
+
+// Tests that a light node rejects malformed proofs from a peer instead of panicking
+func TestLightNodeRejectsMalformedPeer(t *testing.T) {
+	fullNode, _ := New(&protocol.TestNetParams)
+	satoshi := fullNode.NewAccount()
+	fullNode.Initialize(fullNode.EncodeAddress(satoshi.Address))
+	headers, _ := fullNode.Chain.GetBlockHeaders(0)
+	lightNode, _ := NewLight(&protocol.TestNetParams, headers[0])
+
+	proofs, _ := fullNode.GetTransactionProofs(satoshi.Address)
+	noTransaction := *proofs[0]
+	noTransaction.Transaction = nil
+	for _, malformed := range [][]*TransactionProof{{nil}, {&noTransaction}, {proofs[0], nil}} {
+		peer := &malformedPeer{Node: fullNode, proofs: malformed}
+		if lightNode.SyncAddress(peer, lightNode.EncodeAddress(satoshi.Address)) {
+			t.Fatalf(`Synced an address from a peer serving malformed proofs`)
+		}
+	}
+	if lightNode.VerifyTransactionProof(nil) || lightNode.VerifyTransactionProof(&noTransaction) {
+		t.Fatalf(`Verified a malformed proof`)
+	}
+}
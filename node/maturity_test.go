@@ -0,0 +1,62 @@
+package node
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/consensus"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that coinbase outputs cannot be spent until they have enough confirmations
+func TestCoinbaseMaturity(t *testing.T) {
+	params := protocol.TestNetParams
+	node, _ := New(&params)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	miner := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(miner.Address))
+
+	reward := params.ComputeBlockReward(0)
+	if immature := node.Chain.GetImmatureAccountValue(satoshi.Address); immature != reward {
+		t.Fatalf(`Expected the genesis reward of %v to be immature, got %v`, reward, immature)
+	}
+	if tx := node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("1"), util.MustParseAmount("0.1"), nil); tx != nil {
+		t.Fatalf(`Created a transaction spending an immature coinbase`)
+	}
+
+	// Consensus rejects spends of immature coinbase outputs even if a transaction is built anyway
+	params.CoinbaseMaturity = 0
+	premature := node.createPeerTransaction(satoshi, alice.Address, util.MustParseAmount("1"), util.MustParseAmount("0.1"), protocol.MinRelayFeeRate, nil)
+	params.CoinbaseMaturity = protocol.TestNetParams.CoinbaseMaturity
+	if premature == nil || !errors.Is(node.Consensus.ValidatePendingTransaction(node.Chain, premature), consensus.ErrImmatureCoinbase) {
+		t.Fatalf(`Consensus accepted a transaction spending an immature coinbase`)
+	}
+
+	// The genesis coinbase can be spent in the block whose number equals the maturity depth
+	for i := 0; i < params.CoinbaseMaturity-2; i++ {
+		node.MineBlock()
+	}
+	if tx := node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("1"), util.MustParseAmount("0.1"), nil); tx != nil {
+		t.Fatalf(`Created a transaction spending a coinbase one confirmation too early`)
+	}
+
+	node.MineBlock()
+	if immature := node.Chain.GetImmatureAccountValue(satoshi.Address); immature != 0 {
+		t.Fatalf(`Expected no immature value once the coinbase matured, got %v`, immature)
+	}
+	if tx := node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("1"), util.MustParseAmount("0.1"), nil); tx == nil {
+		t.Fatalf(`Failed to spend a mature coinbase`)
+	}
+}
+
+// Mines blocks until the genesis coinbase can be spent in the next block
+func mineToMaturity(t *testing.T, node *Node) {
+	for i := 0; i < node.Params.CoinbaseMaturity-1; i++ {
+		if err := node.MineBlock(); err != nil {
+			t.Fatalf(`Failed to mine block: %v`, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package node
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/AndrewCLu/TestcoinNode/metrics"
+)
+
+var (
+	tipHeight            = metrics.NewGauge("testcoin_chain_tip_height", "Block number of the tip of the chain.")
+	blocksConnected      = metrics.NewCounter("testcoin_chain_blocks_connected_total", "Blocks validated and added to the chain.")
+	blocksRejected       = metrics.NewCounter("testcoin_chain_blocks_rejected_total", "Blocks refused because they failed validation.")
+	mempoolTransactions  = metrics.NewGauge("testcoin_mempool_transactions", "Transactions in the pending pool.")
+	mempoolBytes         = metrics.NewGauge("testcoin_mempool_bytes", "Serialized size of the transactions in the pending pool.")
+	transactionsRejected = metrics.NewCounter("testcoin_mempool_rejected_total", "Transactions refused from the pending pool.")
+)
+
+// Serves the metrics of the node in the Prometheus text format at /metrics on the given address, such as ":9100"
+// Returns the server, whose Addr is the address actually listened on and which can be closed to stop serving,
+// and a bool indicating success
+func (node *Node) ServeMetrics(address string) (*http.Server, bool) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Error("Failed to listen for metrics requests", "address", address, "err", err)
+		return nil, false
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default())
+	server := &http.Server{Addr: listener.Addr().String(), Handler: mux}
+	go server.Serve(listener)
+	log.Info("Serving metrics", "address", server.Addr)
+
+	return server, true
+}
+
+// Updates the gauges that describe the state of the chain and pending pool
+func (node *Node) updateMetrics() {
+	if node.IsLight() {
+		_, blockNum, ok := node.Headers.GetLastHeaderInfo()
+		if ok {
+			tipHeight.Set(float64(blockNum))
+		}
+		return
+	}
+
+	_, blockNum, ok := node.Chain.GetLastBlockInfo()
+	if ok {
+		tipHeight.Set(float64(blockNum))
+	}
+
+	pendingTransactions, _ := node.Chain.GetPendingTransactions(node.Chain.NumPendingTransactions())
+	size := 0
+	for _, tx := range pendingTransactions {
+		size += tx.Size()
+	}
+	mempoolTransactions.Set(float64(len(pendingTransactions)))
+	mempoolBytes.Set(float64(size))
+}
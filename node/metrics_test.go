@@ -0,0 +1,60 @@
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that the metrics endpoint reports the state of the chain, pending pool and miner
+func TestServeMetrics(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+
+	server, ok := node.ServeMetrics("127.0.0.1:0")
+	if !ok {
+		t.Fatalf(`Failed to serve metrics`)
+	}
+	defer server.Close()
+
+	connected := blocksConnected.Value()
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("1"), nil)
+	if mempoolTransactions.Value() != 1 || mempoolBytes.Value() == 0 {
+		t.Fatalf(`Expected one pending transaction, got %v using %v bytes`, mempoolTransactions.Value(), mempoolBytes.Value())
+	}
+
+	node.MineBlock()
+	node.MineBlock()
+	height := node.Params.CoinbaseMaturity + 1
+	if tipHeight.Value() != float64(height) || blocksConnected.Value() != connected+2 || mempoolTransactions.Value() != 0 {
+		t.Fatalf(`Metrics do not match the chain after mining two blocks`)
+	}
+
+	response, err := http.Get("http://" + server.Addr + "/metrics")
+	if err != nil {
+		t.Fatalf(`Failed to scrape metrics: %v`, err)
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+
+	for _, expected := range []string{
+		fmt.Sprintf("testcoin_chain_tip_height %v\n", height),
+		"testcoin_mempool_transactions 0\n",
+		"# TYPE testcoin_consensus_validate_block_seconds histogram\n",
+		"# TYPE testcoin_miner_hashes_total counter\n",
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Fatalf(`Scraped metrics are missing %q:
+%v`, expected, string(body))
+		}
+	}
+}
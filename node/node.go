@@ -1,6 +1,7 @@
 package node
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/AndrewCLu/TestcoinNode/account"
@@ -10,159 +11,564 @@ import (
 	"github.com/AndrewCLu/TestcoinNode/consensus"
 	"github.com/AndrewCLu/TestcoinNode/consensus/pow"
 	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/fees"
+	"github.com/AndrewCLu/TestcoinNode/keystore"
+	"github.com/AndrewCLu/TestcoinNode/logging"
 	"github.com/AndrewCLu/TestcoinNode/miner"
 	"github.com/AndrewCLu/TestcoinNode/protocol"
 	"github.com/AndrewCLu/TestcoinNode/transaction"
 	"github.com/AndrewCLu/TestcoinNode/util"
+	"github.com/AndrewCLu/TestcoinNode/wallet"
+)
+
+const MaxFeeAttempts = 5 // The number of times a transaction is rebuilt to pay for its own size at an estimated fee rate
+
+var genesisPrevHash = crypto.HashBytes([]byte("first")) // The previous block hash every genesis block commits to
+
+var log = logging.Get("node")
+
+// Reasons a node refuses a transaction or block that passes consensus validation
+var (
+	ErrFeeRateBelowMinRelay = errors.New("fee-rate-below-min-relay")
+	ErrNoChain              = errors.New("node does not store the full ledger")
+	ErrTxTooLarge           = errors.New("tx-too-large")
 )
 
 type Node struct {
-	Chain     *chain.Chain
-	Consensus consensus.Consensus
-	Miner     *miner.Miner
+	Params       *protocol.Params   // The parameters of the network this node belongs to
+	Chain        *chain.Chain       // The full ledger, nil for light nodes
+	Headers      *chain.HeaderChain // The header chain, only used by light nodes
+	Consensus    consensus.Consensus
+	Miner        *miner.Miner
+	Wallet       *wallet.Wallet      // Derives the keys of accounts created by this node
+	KeyStore     *keystore.KeyStore  // Stores encrypted account keys, nil if keys are only kept in memory
+	FeeEstimator *fees.Estimator     // Learns fee rates from pending transactions, nil for light nodes
+	Snapshot     *chain.SnapshotInfo // The utxo snapshot the node was started from, nil if it started from genesis
 }
 
-func New() (n *Node, ok bool) {
-	chn, _ := chain.New()
+// Creates a full node for the network with the given parameters
+func New(params *protocol.Params) (n *Node, ok bool) {
+	chn, _ := chain.New(params)
 	pow, _ := pow.New()
+	wallet, walletOk := wallet.New()
+	if !walletOk {
+		return nil, false
+	}
+	estimator, _ := fees.New()
 	node := Node{
-		Chain:     chn,
-		Consensus: pow,
+		Params:       params,
+		Chain:        chn,
+		Consensus:    pow,
+		Wallet:       wallet,
+		FeeEstimator: estimator,
 	}
 
 	return &node, true
 }
 
-// Initializes the node by beginning the chain with the genesis block
-func (node *Node) Initialize(coinbaseAddress common.Address) bool {
-	genesisBlock := GetGenesisBlock(coinbaseAddress)
+// Initializes the node by beginning the chain with the genesis block, paying its reward to a human readable address
+// Returns a bool indicating success, which fails for light nodes or if the address is invalid
+func (node *Node) Initialize(encodedCoinbase string) bool {
+	if node.IsLight() {
+		log.Warn("Light nodes are initialized with a genesis header")
+		return false
+	}
+	coinbaseAddress, ok := node.ParseAddress(encodedCoinbase)
+	if !ok {
+		return false
+	}
+
+	genesisBlock := GetGenesisBlock(node.Params, coinbaseAddress)
 	chainOk := node.Chain.Initialize(genesisBlock)
+	node.updateMetrics()
 
 	return chainOk
 }
 
-// Returns a new account
-// TODO: Key management for accounts
+// Returns a new account derived from the node's wallet, or nil for light nodes, which have no wallet
+// Once the wallet is stored in the keystore, new accounts are locked and only unlocked while signing
 func (node *Node) NewAccount() *account.Account {
-	account, _ := account.New()
-	fmt.Printf("Created account with address: %v\n", account.Address.Hex())
+	if node.IsLight() {
+		log.Warn("Light nodes do not have a wallet")
+		return nil
+	}
+
+	account, ok := node.Wallet.NewAccount()
+	if !ok {
+		log.Error("Failed to derive new account from wallet")
+		return nil
+	}
+	log.Info("Created account", "address", node.Params.EncodeAddress(account.Address))
 
 	return account
 }
 
+// Enables an encrypted keystore in the given directory for storing account keys
+// Returns a bool indicating success
+func (node *Node) EnableKeyStore(directory string) bool {
+	keyStore, ok := keystore.New(directory, node.Params.AddressPrefix)
+	if !ok {
+		log.Error("Failed to open keystore", "directory", directory)
+		return false
+	}
+
+	node.KeyStore = keyStore
+	return true
+}
+
+// Encrypts the node's wallet seed and mnemonic with password into the keystore, then erases them from memory
+// Afterwards the wallet's accounts are locked, and are only unlocked with password while signing
+// Returns a bool indicating success
+func (node *Node) StoreWallet(password string) bool {
+	if node.KeyStore == nil {
+		log.Warn("Keystore is not enabled")
+		return false
+	}
+	if node.Wallet == nil || node.Wallet.IsWatchOnly() {
+		log.Warn("Wallet has no seed to store")
+		return false
+	}
+
+	if !node.KeyStore.StoreWallet(node.Wallet.ExtendedPublicKey(), node.Wallet.Mnemonic, node.Wallet.Seed, password) {
+		log.Error("Failed to store wallet in keystore")
+		return false
+	}
+	node.Wallet.Lock()
+	log.Info("Stored wallet in keystore")
+
+	return true
+}
+
+// Replaces the node's wallet with the locked wallet stored in the keystore, checking that password decrypts it
+// Accounts are derived again in the same order as NewAccount is called
+// Returns a bool indicating success
+func (node *Node) LoadWallet(password string) bool {
+	if node.KeyStore == nil {
+		log.Warn("Keystore is not enabled")
+		return false
+	}
+
+	extendedPublicKey, ok := node.KeyStore.WalletPublicKey()
+	if !ok {
+		log.Warn("Keystore does not contain a wallet")
+		return false
+	}
+	seed, ok := node.KeyStore.UnlockWallet(password)
+	if !ok {
+		log.Warn("Failed to unlock stored wallet")
+		return false
+	}
+	zeroBytes(seed)
+
+	lockedWallet, ok := wallet.NewWatchOnly(extendedPublicKey)
+	if !ok {
+		return false
+	}
+	node.Wallet = lockedWallet
+
+	return true
+}
+
+// Returns a new account derived from the node's wallet whose key is stored in the keystore encrypted with password
+// The wallet itself is stored with password first if it has not been already
+// The returned account is locked, and is only unlocked while signing
+func (node *Node) NewStoredAccount(password string) *account.Account {
+	if node.KeyStore == nil {
+		log.Warn("Keystore is not enabled")
+		return nil
+	}
+	if !node.IsLight() && !node.Wallet.IsWatchOnly() && !node.StoreWallet(password) {
+		return nil
+	}
+
+	act := node.NewAccount()
+	if act == nil {
+		return nil
+	}
+
+	stored := node.signWithWallet(act.Address, password, func(unlocked *account.Account) bool {
+		return node.KeyStore.StoreAccount(unlocked, password)
+	})
+	if !stored {
+		log.Error("Failed to store account in keystore", "address", node.Params.EncodeAddress(act.Address))
+		return nil
+	}
+
+	return act
+}
+
+// Decrypts the wallet seed in the keystore with password, and calls sign with an account of the wallet
+// unlocked only for the duration of the call
+// Returns the result of sign, or false if the account could not be unlocked
+func (node *Node) signWithWallet(address common.Address, password string, sign func(act *account.Account) bool) bool {
+	seed, ok := node.KeyStore.UnlockWallet(password)
+	if !ok {
+		return false
+	}
+	defer zeroBytes(seed)
+
+	return node.Wallet.SignWith(seed, address, sign)
+}
+
+// Overwrites a secret with zeros
+func zeroBytes(secret []byte) {
+	for i := range secret {
+		secret[i] = 0
+	}
+}
+
+// Replaces the node's wallet with one restored from a mnemonic phrase
+// Scans the chain for used addresses, stopping after gapLimit consecutive unused addresses
+// Pruned nodes must have the address index enabled, since pruned blocks can no longer show an address was used
+// Returns the restored accounts and a bool indicating success
+func (node *Node) RestoreWallet(mnemonic string, passphrase string, gapLimit int) ([]*account.Account, bool) {
+	if node.IsLight() {
+		log.Warn("Light nodes cannot scan the chain to restore a wallet")
+		return nil, false
+	}
+	if node.Chain.PruneHeight() > 0 && !node.Chain.HasAddressIndex() {
+		log.Warn("Restoring a wallet on a pruned node requires the address index")
+		return nil, false
+	}
+
+	restoredWallet, ok := wallet.Restore(mnemonic, passphrase, gapLimit, node.Chain)
+	if !ok {
+		log.Warn("Failed to restore wallet from mnemonic")
+		return nil, false
+	}
+
+	node.Wallet = restoredWallet
+	for _, act := range restoredWallet.Accounts {
+		log.Info("Restored account", "address", node.Params.EncodeAddress(act.Address))
+	}
+
+	return restoredWallet.Accounts, true
+}
+
+// Parses a human readable address, rejecting addresses that are corrupted or belong to another network
+// Returns a bool indicating success
+func (node *Node) ParseAddress(encoded string) (common.Address, bool) {
+	address, err := node.Params.DecodeAddress(encoded)
+	if err != nil {
+		log.Warn("Invalid address", "address", encoded, "err", err)
+		return common.Address{}, false
+	}
+
+	return address, true
+}
+
+// Encodes an address into its human readable form on the node's network
+func (node *Node) EncodeAddress(address common.Address) string {
+	return node.Params.EncodeAddress(address)
+}
+
 // Returns a pointer to hard coded genesis block
-func GetGenesisBlock(coinbaseAddress common.Address) *block.Block {
+func GetGenesisBlock(params *protocol.Params, coinbaseAddress common.Address) *block.Block {
 	coinbaseOutput := &transaction.TransactionOutput{
 		ReceiverAddress: coinbaseAddress,
-		Amount:          protocol.ComputeBlockReward(0),
+		Amount:          params.ComputeBlockReward(0),
 	}
 	coinbase, _ := transaction.New(
 		[]*transaction.TransactionInput{},
 		[]*transaction.TransactionOutput{coinbaseOutput},
 	)
-	block, _ := block.New(crypto.HashBytes([]byte("first")), 0, []*transaction.Transaction{}, coinbase)
+	block, _ := block.New(genesisPrevHash, 0, []*transaction.Transaction{}, coinbase)
 
 	return block
 }
 
+// Returns nil if a block could have been created by GetGenesisBlock for the network
+// The genesis coinbase may pay any address, so the block is checked against the network's rules rather than a fixed hash
+func ValidateGenesisBlock(params *protocol.Params, blk *block.Block) error {
+	header := blk.Header
+	if header.ProtocolVersion > protocol.CurrentProtocolVersion {
+		return consensus.ErrBadVersion
+	}
+	if !header.PreviousBlockHash.Equal(genesisPrevHash) {
+		return consensus.ErrBadPrevHash
+	}
+	if len(blk.Body) > 0 {
+		return fmt.Errorf("genesis block has %v transactions besides its coinbase", len(blk.Body))
+	}
+	if len(blk.Coinbase.Inputs) > 0 {
+		return consensus.ErrCoinbaseInputs
+	}
+	if len(blk.Coinbase.Outputs) != 1 {
+		return consensus.ErrCoinbaseOutputs
+	}
+	if blk.Coinbase.Outputs[0].Amount > params.ComputeBlockReward(0) {
+		return consensus.ErrCoinbaseTooLarge
+	}
+	allTransactionsHash := block.ComputeAllTransactionsHash(header.ProtocolVersion, blk.Body, blk.Coinbase)
+	if !header.AllTransactionsHash.Equal(allTransactionsHash) {
+		return consensus.ErrBadMerkleRoot
+	}
+
+	return nil
+}
+
 // Validates a transaction and if valid, adds it to the chain's pool of pending transactions
-// Returns a bool indicating success
-func (node *Node) AddPendingTransaction(tx *transaction.Transaction) bool {
-	validateTx := node.Consensus.ValidatePendingTransaction(node.Chain, tx)
-	if !validateTx {
-		fmt.Println("Failed to validate new transaction, not adding to chain")
-		return false
+// Returns nil on success, or an error explaining why the transaction was refused
+func (node *Node) AddPendingTransaction(tx *transaction.Transaction) error {
+	if node.IsLight() {
+		return ErrNoChain
+	}
+
+	// A transaction larger than a block can never be mined
+	if size := tx.Size(); size > protocol.MaxBlockSize {
+		err := fmt.Errorf("size %v is above %v: %w", size, protocol.MaxBlockSize, ErrTxTooLarge)
+		log.Warn("Refused transaction, not adding to chain", "tx", tx.Hash().Hex(), "err", err)
+		transactionsRejected.Inc()
+		return err
+	}
+
+	if err := node.Consensus.ValidatePendingTransaction(node.Chain, tx); err != nil {
+		log.Warn("Failed to validate new transaction, not adding to chain", "tx", tx.Hash().Hex(), "err", err)
+		transactionsRejected.Inc()
+		return err
+	}
+
+	// Only relay transactions that pay enough per byte to be worth the space they take in a block
+	feeRate, _ := node.Chain.GetPendingTransactionFeeRate(tx)
+	if feeRate < protocol.MinRelayFeeRate {
+		err := fmt.Errorf("fee rate %v is below %v: %w", feeRate, protocol.MinRelayFeeRate, ErrFeeRateBelowMinRelay)
+		log.Warn("Refused transaction, not adding to chain", "tx", tx.Hash().Hex(), "err", err)
+		transactionsRejected.Inc()
+		return err
+	}
+
+	// Track the transaction so the time it waits to be confirmed informs fee estimates
+	// It is tracked before entering the pool, so a block added concurrently cannot confirm it untracked
+	_, blockNum, _ := node.Chain.GetLastBlockInfo()
+	if node.FeeEstimator != nil {
+		node.FeeEstimator.AddTransaction(tx.Hash(), feeRate, blockNum)
 	}
 
 	node.Chain.AddPendingTransaction(tx)
-	return true
+	node.updateMetrics()
+
+	return nil
+}
+
+// Estimates the fee rate, in base units per byte, for a transaction to be confirmed within targetBlocks blocks
+// Returns a bool indicating success, which fails if there is not yet enough data to estimate
+func (node *Node) EstimateFeeRate(targetBlocks int) (uint64, bool) {
+	if node.FeeEstimator == nil {
+		return 0, false
+	}
+
+	return node.FeeEstimator.EstimateFeeRate(targetBlocks)
 }
 
 // Creates a new coinbase transaction for a given account
 // Testing function only, this is only ever created by the miner
-func (node *Node) NewCoinbaseTransaction(account *account.Account, readableAmount float64) *transaction.Transaction {
+func (node *Node) NewCoinbaseTransaction(account *account.Account, amount util.Amount) *transaction.Transaction {
+	if node.IsLight() {
+		return nil
+	}
+
 	address := account.Address
-	amount := util.Float64UnitToUnit64Unit(readableAmount)
 
-	output := &transaction.TransactionOutput{ReceiverAddress: address, Amount: amount}
+	output := &transaction.TransactionOutput{ReceiverAddress: address, Amount: uint64(amount)}
 	newTransaction, success := transaction.New(
 		[]*transaction.TransactionInput{},
 		[]*transaction.TransactionOutput{output},
 	)
 
-	if !success || !node.Consensus.ValidatePendingTransaction(node.Chain, newTransaction) {
-		fmt.Println("Attempted to create new coinbase transaction and FAILED")
+	if !success {
+		log.Error("Attempted to create new coinbase transaction and FAILED")
+		return nil
+	}
+	if err := node.Consensus.ValidatePendingTransaction(node.Chain, newTransaction); err != nil {
+		log.Error("Attempted to create new coinbase transaction and FAILED", "err", err)
 		return nil
 	}
 
-	fmt.Printf("Created new coinbase transaction %v sending %v to %v\n",
-		newTransaction.Hash().Hex(),
-		readableAmount,
-		address.Hex(),
+	log.Info("Created new coinbase transaction",
+		"tx", newTransaction.Hash().Hex(),
+		"amount", amount,
+		"receiver", node.Params.EncodeAddress(address),
 	)
 
 	return newTransaction
 }
 
-// Creates a new peer transaction for a given amount
-// Readable indicates that the units taken in by this function are in decimal units, which need to be converted to integer units before sending
-func (node *Node) NewPeerTransaction(account *account.Account, receiverAddress common.Address, readableAmount float64, readableTransactionFee float64) *transaction.Transaction {
+// Creates a new peer transaction paying a given amount to a human readable address
+// Amounts can be parsed from human readable decimal strings with util.ParseAmount
+// The selector chooses which unspent outputs to spend, or the wallet's default selector is used if it is nil
+func (node *Node) NewPeerTransaction(account *account.Account, encodedReceiver string, amount util.Amount, transactionFee util.Amount, selector wallet.CoinSelector) *transaction.Transaction {
+	if node.IsLight() {
+		log.Warn("Light nodes cannot select unspent outputs to create transactions")
+		return nil
+	}
+	receiverAddress, ok := node.ParseAddress(encodedReceiver)
+	if !ok {
+		return nil
+	}
+
+	// A fixed fee is taken to pay for a transaction with one input, a payment output and a change output
+	feeRate := uint64(transactionFee) / uint64(wallet.EstimatedSize(1, 2))
+	newTransaction := node.createPeerTransaction(account, receiverAddress, amount, transactionFee, feeRate, selector)
+	if newTransaction == nil {
+		return nil
+	}
+
+	// The pending pool can refuse a valid transaction, which is then never mined, so it is not returned as sent
+	if err := node.AddPendingTransaction(newTransaction); err != nil {
+		log.Warn("Created new peer transaction but the pending pool refused it", "tx", newTransaction.Hash().Hex(), "err", err)
+		return nil
+	}
+	log.Info("Created new peer transaction",
+		"tx", newTransaction.Hash().Hex(),
+		"amount", amount,
+		"sender", node.Params.EncodeAddress(account.Address),
+		"receiver", node.Params.EncodeAddress(receiverAddress),
+		"fee", transactionFee,
+	)
+
+	return newTransaction
+}
+
+// Creates a new peer transaction paying a human readable address, whose fee is estimated so that it is likely
+// to be confirmed within targetBlocks blocks
+// The fee is raised until it pays the estimated fee rate for the transaction's actual size
+func (node *Node) NewPeerTransactionWithFeeTarget(account *account.Account, encodedReceiver string, amount util.Amount, targetBlocks int, selector wallet.CoinSelector) *transaction.Transaction {
+	if node.IsLight() {
+		log.Warn("Light nodes cannot select unspent outputs to create transactions")
+		return nil
+	}
+	receiverAddress, ok := node.ParseAddress(encodedReceiver)
+	if !ok {
+		return nil
+	}
+
+	// Start from the fee of a transaction with one input, a payment output and a change output
+	estimatedFee, feeRate, ok := wallet.EstimateFee(node, targetBlocks, 1, 2)
+	if !ok {
+		log.Warn("Attempted to create new peer transaction but could not estimate a fee rate", "targetBlocks", targetBlocks)
+		return nil
+	}
+
+	transactionFee := util.Amount(estimatedFee)
+	for attempt := 0; attempt < MaxFeeAttempts; attempt++ {
+		newTransaction := node.createPeerTransaction(account, receiverAddress, amount, transactionFee, feeRate, selector)
+		if newTransaction == nil {
+			return nil
+		}
+
+		requiredFee := util.Amount(wallet.FeeForSize(feeRate, newTransaction.Size()))
+		if transactionFee < requiredFee {
+			transactionFee = requiredFee
+			continue
+		}
+
+		if err := node.AddPendingTransaction(newTransaction); err != nil {
+			log.Warn("Created new peer transaction but the pending pool refused it", "tx", newTransaction.Hash().Hex(), "err", err)
+			return nil
+		}
+		log.Info("Created new peer transaction",
+			"tx", newTransaction.Hash().Hex(),
+			"amount", amount,
+			"sender", node.Params.EncodeAddress(account.Address),
+			"receiver", node.Params.EncodeAddress(receiverAddress),
+			"fee", transactionFee,
+			"feeRate", feeRate,
+		)
+
+		return newTransaction
+	}
+
+	log.Warn("Attempted to create new peer transaction but could not settle on a fee", "attempts", MaxFeeAttempts)
+	return nil
+}
+
+// Builds and signs a peer transaction paying amount to the receiver and transactionFee to the miner
+// Coins are selected counting the cost of each input and of change at feeRate, in base units per byte
+// The transaction is validated but not added to the pending pool
+func (node *Node) createPeerTransaction(account *account.Account, receiverAddress common.Address, amount util.Amount, transactionFee util.Amount, feeRate uint64, selector wallet.CoinSelector) *transaction.Transaction {
+	if node.IsLight() {
+		return nil
+	}
+
+	if account.IsLocked() {
+		log.Warn("Attempted to create new peer transaction but sender account is locked", "sender", node.Params.EncodeAddress(account.Address))
+		return nil
+	}
+
+	total, totalOk := amount.Add(transactionFee)
+	if !totalOk {
+		log.Warn("Attempted to create new peer transaction but amount plus fee overflows", "amount", amount, "fee", transactionFee)
+		return nil
+	}
+
 	senderAddress := account.Address
 	senderPublicKey := account.PublicKey
 	senderPrivateKey := account.PrivateKey
-	amount := util.Float64UnitToUnit64Unit(readableAmount)
-	transactionFee := util.Float64UnitToUnit64Unit(readableTransactionFee)
 
-	// Check that sender has enough money
-	senderValue := node.Chain.GetAccountValue(senderAddress)
-	if senderValue < amount+transactionFee {
-		fmt.Println("Attempted to create new peer transaction but sender has insufficient funds.")
+	// Check that sender has enough money that can be spent
+	senderValue := node.Chain.GetAccountValue(senderAddress) - node.Chain.GetImmatureAccountValue(senderAddress)
+	if senderValue < uint64(total) {
+		log.Warn("Attempted to create new peer transaction but sender has insufficient funds",
+			"sender", node.Params.EncodeAddress(senderAddress),
+			"spendable", util.Amount(senderValue),
+			"required", total,
+		)
 		return nil
 	}
 
 	allUtxos, _ := node.Chain.GetUnspentTransactions(senderAddress)
 	pendingTransactions, _ := node.Chain.GetPendingTransactionsByAddress(senderAddress)
-	pendingUtxos := []*transaction.TransactionOutputPointer{}
-	selectedUtxos := []*transaction.TransactionOutputPointer{}
-	var currentAmount uint64 = 0
-	// Two step process: First select Utxos that are not used in a pending transaction
-	// If all utxos are used, then select ones that are part of a pending transaction
-finishedSelection:
+	availableCoins := []*wallet.Coin{}
+	allCoins := []*wallet.Coin{}
 	for _, utxo := range allUtxos {
+		// Coinbase outputs without enough confirmations cannot be spent yet
+		if !node.Chain.IsOutputMature(utxo) {
+			continue
+		}
+
+		utxoAmount, _ := node.Chain.GetOutputAmount(utxo)
+		coin := &wallet.Coin{OutputPointer: utxo, Amount: utxoAmount}
+		allCoins = append(allCoins, coin)
+
+		// Utxo is already pending, don't add to this transaction unless we cannot achieve the desired amount otherwise
 		match := false
-	nextUtxo:
 		for _, tx := range pendingTransactions {
 			for _, input := range tx.Inputs {
-				// Utxo is already pending, don't add to this transaction unless we cannot achieve the desired amount otherwise
 				if input.OutputPointer.Equal(utxo) {
 					match = true
-					pendingUtxos = append(pendingUtxos, utxo)
-					break nextUtxo
 				}
 			}
 		}
-		// Utxo is not pending, add to this transaction
 		if !match {
-			selectedUtxos = append(selectedUtxos, utxo)
-			newAmount, _ := node.Chain.GetOutputAmount(utxo)
-			currentAmount += newAmount
+			availableCoins = append(availableCoins, coin)
 		}
+	}
 
-		if currentAmount >= amount+transactionFee {
-			break finishedSelection
-		}
+	if selector == nil {
+		selector = wallet.DefaultCoinSelector()
+	}
+	// The fee already pays for the first input, so only the inputs after it have to cover their own cost
+	selectionParams := wallet.NewCoinSelectionParams(uint64(total), feeRate)
+	if selectionParams.CostPerInput < selectionParams.Target {
+		selectionParams.Target -= selectionParams.CostPerInput
 	}
-	// Add utxos that are part of a pending trnasaction if the amount has not been reached
-	if currentAmount < amount+transactionFee {
-		for _, utxo := range pendingUtxos {
-			selectedUtxos = append(selectedUtxos, utxo)
-			newAmount, _ := node.Chain.GetOutputAmount(utxo)
-			currentAmount += newAmount
 
-			if currentAmount >= amount+transactionFee {
-				break
-			}
-		}
+	// Two step process: First select Utxos that are not used in a pending transaction
+	// If those cannot cover the amount, then also select ones that are part of a pending transaction
+	selectedCoins, selectionOk := selector.SelectCoins(availableCoins, selectionParams)
+	if !selectionOk {
+		selectedCoins, selectionOk = selector.SelectCoins(allCoins, selectionParams)
+	}
+	if !selectionOk {
+		log.Warn("Attempted to create new peer transaction but could not select enough unspent outputs", "sender", node.Params.EncodeAddress(senderAddress))
+		return nil
+	}
+
+	selectedUtxos := []*transaction.TransactionOutputPointer{}
+	var currentAmount uint64 = 0
+	for _, coin := range selectedCoins {
+		selectedUtxos = append(selectedUtxos, coin.OutputPointer)
+		currentAmount += coin.Amount
 	}
 
 	inputs := []*transaction.TransactionInput{}
@@ -184,11 +590,11 @@ finishedSelection:
 	}
 
 	// If sender has more money than amount, create a refund transaction output
-	diff := currentAmount - amount - transactionFee
+	diff := currentAmount - uint64(total)
 
 	outputReceiver := &transaction.TransactionOutput{
 		ReceiverAddress: receiverAddress,
-		Amount:          amount,
+		Amount:          uint64(amount),
 	}
 	outputSender := &transaction.TransactionOutput{
 		ReceiverAddress: senderAddress,
@@ -202,75 +608,193 @@ finishedSelection:
 
 	newTransaction, success := transaction.New(inputs, outputs)
 
-	if !success || !node.Consensus.ValidatePendingTransaction(node.Chain, newTransaction) {
+	if !success {
+		log.Error("Attempted to create new peer transaction and FAILED")
+		return nil
+	}
+	if err := node.Consensus.ValidatePendingTransaction(node.Chain, newTransaction); err != nil {
 		node.PrintTransaction(newTransaction)
-		fmt.Println("Attempted to create new peer transaction and FAILED")
+		log.Warn("Attempted to create new peer transaction and FAILED", "tx", newTransaction.Hash().Hex(), "err", err)
 		return nil
 	}
 
-	fmt.Printf("Created new peer transaction %v sending %v from %v to %v with transaction fee of %v\n",
-		newTransaction.Hash().Hex(),
-		readableAmount,
-		senderAddress.Hex(),
-		receiverAddress.Hex(),
-		readableTransactionFee,
-	)
+	return newTransaction
+}
+
+// Creates a new peer transaction between human readable addresses, sent from an account in the keystore
+// or an account of the wallet stored in the keystore
+// The account is unlocked with password only while the transaction is signed
+func (node *Node) NewPeerTransactionFromKeyStore(encodedSender string, password string, encodedReceiver string, amount util.Amount, transactionFee util.Amount, selector wallet.CoinSelector) *transaction.Transaction {
+	if node.KeyStore == nil {
+		log.Warn("Keystore is not enabled")
+		return nil
+	}
+	senderAddress, ok := node.ParseAddress(encodedSender)
+	if !ok {
+		return nil
+	}
+
+	var newTransaction *transaction.Transaction
+	sign := func(account *account.Account) bool {
+		newTransaction = node.NewPeerTransaction(account, encodedReceiver, amount, transactionFee, selector)
+		return newTransaction != nil
+	}
+	if node.KeyStore.HasAddress(encodedSender) {
+		node.KeyStore.SignWith(encodedSender, password, sign)
+	} else if node.KeyStore.HasWallet() && node.Wallet != nil {
+		node.signWithWallet(senderAddress, password, sign)
+	} else {
+		log.Warn("Keystore does not contain the sender's key", "sender", encodedSender)
+	}
 
-	node.AddPendingTransaction(newTransaction)
 	return newTransaction
 }
 
-// Initializes the miner with specified coinbase address
-func (node *Node) BeginMiner(coinbase common.Address) {
-	node.Miner, _ = miner.New(coinbase, node.Chain, node.Consensus)
+// Initializes the miner to pay block rewards to a human readable address
+// Light nodes do not store the full ledger and cannot mine
+// Returns a bool indicating success
+func (node *Node) BeginMiner(encodedCoinbase string) bool {
+	if node.IsLight() {
+		return false
+	}
+	coinbase, ok := node.ParseAddress(encodedCoinbase)
+	if !ok {
+		return false
+	}
+
+	node.Miner, ok = miner.New(coinbase, node.Chain, node.Consensus)
+	return ok
 }
 
 // Calls the miner to mine a block and adds it to the chain if it is valid
-func (node *Node) MineBlock() {
+// Returns nil on success, or an error explaining why the block was refused
+func (node *Node) MineBlock() error {
 	if node.Miner == nil {
-		return
+		return errors.New("miner has not been started")
 	}
 	block, ok := node.Miner.MineBlock()
 	if !ok {
-		return
+		return errors.New("miner failed to produce a block")
+	}
+
+	return node.SubmitBlock(block)
+}
+
+// Validates a block and if valid, adds it to the chain and drops pending transactions it invalidates
+// Returns nil on success, or an error explaining why the block was refused
+func (node *Node) SubmitBlock(block *block.Block) error {
+	if node.IsLight() {
+		return ErrNoChain
 	}
 
-	valid := node.Consensus.ValidateBlock(node.Chain, block)
-	if valid {
-		node.Chain.AddBlock(block)
+	if err := node.Consensus.ValidateBlock(node.Chain, block); err != nil {
+		log.Warn("Refused block", "hash", block.Hash().Hex(), "err", err)
+		blocksRejected.Inc()
+		return err
 	}
 
+	if !node.Chain.AddBlock(block) {
+		return errors.New("could not add block to chain")
+	}
+	node.processBlockFees(block)
 	node.RemoveInvalidPendingTransactions()
+	blocksConnected.Inc()
+	node.updateMetrics()
+
+	return nil
 }
 
 // Removes all invalid pending transactions given the current state of the chain
 // Returns a bool indicating success
 func (node *Node) RemoveInvalidPendingTransactions() bool {
+	pendingTransactions, _ := node.Chain.GetPendingTransactions(node.Chain.NumPendingTransactions())
 	invalidTransactions := []*transaction.Transaction{}
-	for _, tx := range node.Chain.PendingTransactions {
-		if !node.Consensus.ValidatePendingTransaction(node.Chain, tx) {
+	for _, tx := range pendingTransactions {
+		if node.Consensus.ValidatePendingTransaction(node.Chain, tx) != nil {
 			invalidTransactions = append(invalidTransactions, tx)
 		}
 	}
 
+	if node.FeeEstimator != nil {
+		for _, tx := range invalidTransactions {
+			node.FeeEstimator.RemoveTransaction(tx.Hash())
+		}
+	}
+
 	ok := node.Chain.RemovePendingTransactions(invalidTransactions)
 	return ok
 }
 
-// Gets the human readable value of an account
-func (node *Node) GetReadableAccountValue(account *account.Account) float64 {
+// Tells the fee estimator which pending transactions were confirmed by a newly added block
+func (node *Node) processBlockFees(blk *block.Block) {
+	if node.FeeEstimator == nil {
+		return
+	}
+
+	_, blockNum, _ := node.Chain.GetLastBlockInfo()
+	txHashes := []common.Hash{}
+	for _, tx := range blk.Body {
+		txHashes = append(txHashes, tx.Hash())
+	}
+	node.FeeEstimator.ProcessBlock(blockNum, txHashes)
+}
+
+// Gets the value of an account, which formats as a human readable number of coins
+// Light nodes do not track every unspent output, so their value is 0
+func (node *Node) GetReadableAccountValue(account *account.Account) util.Amount {
+	if node.IsLight() {
+		log.Warn("Light nodes do not track every unspent output")
+		return 0
+	}
+
 	address := account.Address
 
-	total := node.Chain.GetAccountValue(address)
-	readableTotal := util.Uint64UnitToFloat64Unit(total)
+	total := util.Amount(node.Chain.GetAccountValue(address))
+	immature := util.Amount(node.Chain.GetImmatureAccountValue(address))
+
+	log.Debug("Read account value", "address", node.Params.EncodeAddress(address), "value", total, "immature", immature)
+
+	return total
+}
 
-	fmt.Printf("Account with address %v has value %v\n", address.Hex(), readableTotal)
+// Gets the value of an account's coinbase outputs that cannot be spent yet
+func (node *Node) GetReadableImmatureAccountValue(account *account.Account) util.Amount {
+	if node.IsLight() {
+		log.Warn("Light nodes do not track every unspent output")
+		return 0
+	}
 
-	return readableTotal
+	return util.Amount(node.Chain.GetImmatureAccountValue(account.Address))
+}
+
+// Gets the number of coins the emission schedule has issued up to the tip of the chain
+func (node *Node) GetReadableIssuedSupply() util.Amount {
+	var lastBlockNum int
+	if node.IsLight() {
+		_, lastBlockNum, _ = node.Headers.GetLastHeaderInfo()
+	} else {
+		_, lastBlockNum, _ = node.Chain.GetLastBlockInfo()
+	}
+
+	return util.Amount(node.Params.ComputeIssuedSupply(lastBlockNum))
+}
+
+// Gets the number of coins held in unspent outputs
+// This is less than the issued supply if miners did not claim their full reward
+func (node *Node) GetReadableCirculatingSupply() util.Amount {
+	if node.IsLight() {
+		log.Warn("Light nodes do not track every unspent output")
+		return 0
+	}
+
+	return util.Amount(node.Chain.GetCirculatingSupply())
 }
 
 // Testing function to print the state of the chain
 func (node *Node) PrintChainState() {
+	if node.IsLight() {
+		return
+	}
 	node.Chain.PrintChainState()
 }
 
@@ -284,7 +808,7 @@ func (node *Node) PrintTransaction(tx *transaction.Transaction) {
 	}
 	fmt.Printf("Outputs: ")
 	for _, output := range tx.Outputs {
-		fmt.Printf("%v to address %v ", output.Amount, output.ReceiverAddress.Hex())
+		fmt.Printf("%v to address %v ", output.Amount, node.Params.EncodeAddress(output.ReceiverAddress))
 	}
 	fmt.Printf("\n")
 }
@@ -0,0 +1,47 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/chain"
+)
+
+// Reasons a node cannot serve a block
+var (
+	ErrBlockNotFound = errors.New("block not found")
+	ErrBlockPruned   = errors.New("block body has been pruned")
+)
+
+// Starts deleting old block bodies and undo data, keeping headers and the utxo set
+// Returns a bool indicating success, which fails for light nodes or if the settings have no limit
+func (node *Node) EnablePruning(config chain.PruneConfig) bool {
+	if node.IsLight() {
+		return false
+	}
+	if !node.Chain.EnablePruning(config) {
+		return false
+	}
+	log.Info("Enabled pruning", "depth", config.Depth, "maxBytes", config.MaxBytes)
+
+	return true
+}
+
+// Gets the full block with the given number
+// Returns the block, or an error if the node is light, the block does not exist, or its body has been pruned
+func (node *Node) GetBlock(blockNum int) (*block.Block, error) {
+	if node.IsLight() {
+		return nil, ErrNoChain
+	}
+
+	blk, ok := node.Chain.GetBlockByNumber(blockNum)
+	if ok {
+		return blk, nil
+	}
+	if node.Chain.IsBlockPruned(blockNum) {
+		return nil, fmt.Errorf("block %v is below prune height %v: %w", blockNum, node.Chain.PruneHeight(), ErrBlockPruned)
+	}
+
+	return nil, fmt.Errorf("block %v: %w", blockNum, ErrBlockNotFound)
+}
@@ -0,0 +1,75 @@
+package node
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/chain"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that a pruning node serves recent blocks and clearly refuses pruned ones
+func TestPruning(t *testing.T) {
+	node, _ := New(&protocol.DevNetParams)
+	satoshi := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	for i := 0; i < 8; i++ {
+		if err := node.MineBlock(); err != nil {
+			t.Fatalf(`Failed to mine block: %v`, err)
+		}
+	}
+
+	if !node.EnablePruning(chain.PruneConfig{Depth: chain.MinPruneDepth}) {
+		t.Fatalf(`Failed to enable pruning`)
+	}
+	if _, err := node.GetBlock(0); !errors.Is(err, ErrBlockPruned) {
+		t.Fatalf(`Expected a pruned block error, got %v`, err)
+	}
+	if _, err := node.GetBlock(8); err != nil {
+		t.Fatalf(`Failed to get the tip: %v`, err)
+	}
+	if _, err := node.GetBlock(9); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf(`Expected a missing block error, got %v`, err)
+	}
+	if node.EnableIndexes() {
+		t.Fatalf(`Enabled indexes on a pruned chain`)
+	}
+	if _, ok := node.GetTransactionProofs(satoshi.Address); ok {
+		t.Fatalf(`Served transaction proofs that leave out pruned blocks`)
+	}
+
+	// Pruned blocks cannot show which addresses were used, so restoring needs the address index
+	if _, ok := node.RestoreWallet(node.Wallet.Mnemonic, "", 5); ok {
+		t.Fatalf(`Restored a wallet on a pruned node without the address index`)
+	}
+}
+
+// Tests that a pruned node with the address index restores addresses whose outputs were spent in pruned blocks
+func TestPrunedRestoreWithAddressIndex(t *testing.T) {
+	node, _ := New(&protocol.DevNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.EnableIndexes()
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+
+	// Alice receives coins and spends all of them back before her block is pruned
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("1"), nil)
+	node.MineBlock()
+	node.NewPeerTransaction(alice, node.EncodeAddress(satoshi.Address), util.MustParseAmount("2"), util.MustParseAmount("1"), nil)
+	node.MineBlock()
+	for i := 0; i < 2*chain.MinPruneDepth; i++ {
+		node.MineBlock()
+	}
+	node.EnablePruning(chain.PruneConfig{Depth: chain.MinPruneDepth})
+	if node.Chain.GetAccountValue(alice.Address) != 0 || !node.Chain.IsBlockPruned(2) {
+		t.Fatalf(`Expected alice's outputs to be spent in a pruned block`)
+	}
+
+	restored, ok := node.RestoreWallet(node.Wallet.Mnemonic, "", 5)
+	if !ok || len(restored) != 2 || !restored[1].Address.Equal(alice.Address) {
+		t.Fatalf(`Failed to restore an address only used in pruned blocks`)
+	}
+}
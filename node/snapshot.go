@@ -0,0 +1,118 @@
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/AndrewCLu/TestcoinNode/block"
+	"github.com/AndrewCLu/TestcoinNode/chain"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+)
+
+// A block source supplies full blocks by number, such as a peer or a node that keeps full history
+type BlockSource interface {
+	GetBlock(blockNum int) (*block.Block, error)
+}
+
+// Writes a snapshot of the utxo set as of the given block to a file
+// Returns the snapshot's description, or an error if the node is light, the block is unknown or pruned, or the file cannot be written
+func (node *Node) ExportSnapshot(path string, blockNum int) (chain.SnapshotInfo, error) {
+	if node.IsLight() {
+		return chain.SnapshotInfo{}, ErrNoChain
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return chain.SnapshotInfo{}, err
+	}
+	info, ok := node.Chain.ExportSnapshot(file, blockNum)
+	if closeErr := file.Close(); closeErr != nil && ok {
+		return chain.SnapshotInfo{}, closeErr
+	}
+	if !ok {
+		os.Remove(path)
+		return chain.SnapshotInfo{}, fmt.Errorf("cannot export a snapshot at block %v", blockNum)
+	}
+	log.Info("Exported utxo snapshot", "path", path, "hash", info.Hash.Hex(), "height", info.BlockNum, "utxos", info.NumUtxos)
+
+	return info, nil
+}
+
+// Creates a full node whose chain starts from the snapshot in the given file instead of the genesis block
+// The snapshot is only trusted if its hash is pinned in params
+// Returns the node, or an error explaining why the snapshot was refused
+func NewFromSnapshot(params *protocol.Params, path string) (*Node, error) {
+	node, ok := New(params)
+	if !ok {
+		return nil, fmt.Errorf("could not create node")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := node.Chain.LoadSnapshot(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not load snapshot %v: %w", path, err)
+	}
+	node.Snapshot = &info
+	node.updateMetrics()
+
+	return node, nil
+}
+
+// Replays the history below the node's snapshot from source in the background, checking it rebuilds the same utxo set
+// The returned channel receives nil if history matches the snapshot, or an error explaining why it does not
+func (node *Node) ValidateSnapshotHistory(source BlockSource) <-chan error {
+	result := make(chan error, 1)
+	if node.Snapshot == nil {
+		result <- fmt.Errorf("node was not started from a snapshot")
+		return result
+	}
+
+	info := *node.Snapshot
+	headers, _ := node.Chain.GetBlockHeaders(0)
+	go func() {
+		err := node.validateSnapshotHistory(source, info, headers)
+		if err != nil {
+			log.Error("Snapshot history is invalid", "hash", info.Hash.Hex(), "err", err)
+		} else {
+			log.Info("Validated snapshot history", "hash", info.Hash.Hex(), "height", info.BlockNum)
+		}
+		result <- err
+	}()
+
+	return result
+}
+
+func (node *Node) validateSnapshotHistory(source BlockSource, info chain.SnapshotInfo, headers []*block.BlockHeader) error {
+	replay, _ := chain.New(node.Params)
+	for blockNum := 0; blockNum <= info.BlockNum; blockNum++ {
+		blk, err := source.GetBlock(blockNum)
+		if err != nil {
+			return fmt.Errorf("could not get block %v: %w", blockNum, err)
+		}
+		if !blk.Hash().Equal(headers[blockNum].Hash()) {
+			return fmt.Errorf("block %v does not match the snapshot's header", blockNum)
+		}
+
+		if blockNum == 0 {
+			replay.Initialize(blk)
+			continue
+		}
+		if err := node.Consensus.ValidateBlock(replay, blk); err != nil {
+			return fmt.Errorf("block %v is invalid: %w", blockNum, err)
+		}
+		replay.AddBlock(blk)
+	}
+
+	replayed, _ := replay.ExportSnapshot(ioutil.Discard, info.BlockNum)
+	if !replayed.Hash.Equal(info.Hash) {
+		return fmt.Errorf("replayed utxo set has hash %v, expected %v", replayed.Hash.Hex(), info.Hash.Hex())
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that a node started from a pinned snapshot has the same balances, keeps mining and validates the history behind it
+func TestSnapshot(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("0.01"), nil)
+	node.MineBlock()
+	node.MineBlock()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "utxo.snapshot")
+	_, height, _ := node.Chain.GetLastBlockInfo()
+	info, err := node.ExportSnapshot(path, height)
+	if err != nil {
+		t.Fatalf(`Failed to export snapshot: %v`, err)
+	}
+
+	if _, err := NewFromSnapshot(&protocol.TestNetParams, path); err == nil {
+		t.Fatalf(`Started from a snapshot whose hash is not pinned`)
+	}
+	params := protocol.TestNetParams
+	params.SnapshotHashes = map[int]common.Hash{height: info.Hash}
+	restored, err := NewFromSnapshot(&params, path)
+	if err != nil {
+		t.Fatalf(`Failed to start from snapshot: %v`, err)
+	}
+	if restored.Chain.GetAccountValue(alice.Address) != node.Chain.GetAccountValue(alice.Address) ||
+		restored.Chain.GetAccountValue(satoshi.Address) != node.Chain.GetAccountValue(satoshi.Address) {
+		t.Fatalf(`Restored node has different balances`)
+	}
+
+	restored.BeginMiner(restored.EncodeAddress(satoshi.Address))
+	if err := restored.MineBlock(); err != nil {
+		t.Fatalf(`Failed to mine on top of the snapshot: %v`, err)
+	}
+
+	if err := <-restored.ValidateSnapshotHistory(node); err != nil {
+		t.Fatalf(`Failed to validate snapshot history: %v`, err)
+	}
+
+	// A source whose history differs from the snapshot is caught
+	other, _ := New(&protocol.TestNetParams)
+	other.Initialize(other.EncodeAddress(alice.Address))
+	if err := <-restored.ValidateSnapshotHistory(other); err == nil {
+		t.Fatalf(`Validated snapshot against a different history`)
+	}
+}
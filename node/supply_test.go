@@ -0,0 +1,30 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that the node reports the issued and circulating supply as blocks are mined
+func TestSupply(t *testing.T) {
+	params := protocol.DevNetParams
+	params.Emission = protocol.EmissionSchedule{InitialReward: uint64(util.MustParseAmount("10")), HalvingInterval: 2}
+	node, _ := New(&params)
+	satoshi := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+
+	for i := 0; i < 3; i++ {
+		node.MineBlock()
+	}
+
+	// Blocks 0 and 1 reward 10 coins, blocks 2 and 3 reward 5 coins
+	if issued := node.GetReadableIssuedSupply(); issued != util.MustParseAmount("30") {
+		t.Fatalf(`Expected 30 coins issued, got %v`, issued)
+	}
+	if circulating := node.GetReadableCirculatingSupply(); circulating != util.MustParseAmount("30") {
+		t.Fatalf(`Expected 30 coins in circulation, got %v`, circulating)
+	}
+}
@@ -0,0 +1,26 @@
+package node
+
+import (
+	"github.com/AndrewCLu/TestcoinNode/chain"
+)
+
+// Re-walks the chain from genesis to check it is self-consistent, rebuilding the utxo set to compare with the chain's
+// The last depth blocks, or every block if depth is 0, also have their hashes, transactions and proof of work checked
+// Returns a report of every inconsistency found, or ErrNoChain for light nodes
+func (node *Node) VerifyChain(depth int) (*chain.VerifyReport, error) {
+	if node.IsLight() {
+		return nil, ErrNoChain
+	}
+
+	report := node.Chain.Verify(depth, node.Consensus.ValidateBlockHeader)
+	if report.OK() {
+		log.Info("Verified chain", "from", report.FromBlock, "to", report.TipNum, "utxos", report.NumUtxos, "utxosChecked", report.UtxosChecked)
+	} else {
+		log.Error("Chain is inconsistent", "from", report.FromBlock, "to", report.TipNum, "mismatches", len(report.Mismatches))
+		for _, mismatch := range report.Mismatches {
+			log.Error("Chain mismatch", "height", mismatch.BlockNum, "kind", mismatch.Kind, "detail", mismatch.Detail)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,34 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
+)
+
+// Tests that a mined chain verifies, including the proof of work of every block
+func TestVerifyChain(t *testing.T) {
+	node, _ := New(&protocol.TestNetParams)
+	satoshi := node.NewAccount()
+	alice := node.NewAccount()
+	node.Initialize(node.EncodeAddress(satoshi.Address))
+	node.BeginMiner(node.EncodeAddress(satoshi.Address))
+	mineToMaturity(t, node)
+	node.NewPeerTransaction(satoshi, node.EncodeAddress(alice.Address), util.MustParseAmount("3"), util.MustParseAmount("0.01"), nil)
+	node.MineBlock()
+	node.MineBlock()
+
+	report, err := node.VerifyChain(0)
+	if err != nil || !report.OK() || report.BlocksChecked != node.Params.CoinbaseMaturity+2 || !report.UtxosChecked {
+		t.Fatalf(`Failed to verify a mined chain: %v`, report)
+	}
+
+	// A header that no longer meets its target is reported
+	_, lastBlockNum, _ := node.Chain.GetLastBlockInfo()
+	blk, _ := node.Chain.GetBlockByNumber(lastBlockNum)
+	blk.Header.Nonce++
+	if report, _ := node.VerifyChain(1); report.OK() {
+		t.Fatalf(`Verified a block whose header was changed`)
+	}
+}
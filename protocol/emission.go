@@ -0,0 +1,84 @@
+package protocol
+
+import "math"
+
+// An emission schedule determines how many new coins each block's coinbase may create
+// The reward starts at InitialReward and halves every HalvingInterval blocks, but never drops below TerminalReward
+// If MaxSupply is set, rewards stop once that many coins have been issued
+type EmissionSchedule struct {
+	InitialReward   uint64 // The reward of the genesis block, in base units
+	HalvingInterval int    // The number of blocks between halvings, or 0 if the reward never halves
+	TerminalReward  uint64 // The smallest reward a block can have before the supply cap is reached, in base units
+	MaxSupply       uint64 // The most coins that will ever be issued, in base units, or 0 if there is no cap
+}
+
+// The emission schedule of the Testcoin networks
+var DefaultEmissionSchedule = EmissionSchedule{
+	InitialReward:   10 * TestcoinUnitMultiplier,
+	HalvingInterval: 210000,
+	TerminalReward:  0,
+	MaxSupply:       0,
+}
+
+// Given the current block number, return the appropriate coinbase reward for mining a block
+func (params *Params) ComputeBlockReward(blockNumber int) uint64 {
+	if blockNumber < 0 {
+		return 0
+	}
+
+	return params.ComputeIssuedSupply(blockNumber) - params.ComputeIssuedSupply(blockNumber-1)
+}
+
+// Returns the total number of coins, in base units, issued by the coinbases of blocks 0 through blockNumber
+func (params *Params) ComputeIssuedSupply(blockNumber int) uint64 {
+	schedule := &params.Emission
+
+	var supply uint64 = 0
+	for start := 0; start <= blockNumber; {
+		// Every block from start to end has the same scheduled reward
+		end := blockNumber
+		halvings := 0
+		if schedule.HalvingInterval > 0 {
+			halvings = start / schedule.HalvingInterval
+			eraEnd := (halvings+1)*schedule.HalvingInterval - 1
+			if eraEnd < end {
+				end = eraEnd
+			}
+		}
+
+		// Once the reward stops changing, every remaining block has the same reward
+		reward := schedule.scheduledReward(halvings)
+		if schedule.scheduledReward(halvings+1) == reward {
+			end = blockNumber
+		}
+
+		count := uint64(end - start + 1)
+		if reward > 0 && count > (math.MaxUint64-supply)/reward {
+			supply = math.MaxUint64
+		} else {
+			supply += reward * count
+		}
+
+		if schedule.MaxSupply > 0 && supply >= schedule.MaxSupply {
+			return schedule.MaxSupply
+		}
+
+		start = end + 1
+	}
+
+	return supply
+}
+
+// Returns the reward of a block after the given number of halvings, before the supply cap is applied
+func (schedule *EmissionSchedule) scheduledReward(halvings int) uint64 {
+	reward := uint64(0)
+	if halvings < 64 {
+		reward = schedule.InitialReward >> uint(halvings)
+	}
+
+	if reward < schedule.TerminalReward {
+		return schedule.TerminalReward
+	}
+
+	return reward
+}
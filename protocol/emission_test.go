@@ -0,0 +1,61 @@
+package protocol
+
+import "testing"
+
+// Tests that the reward halves on schedule and the issued supply sums the rewards
+func TestHalvingSchedule(t *testing.T) {
+	params := Params{Emission: EmissionSchedule{InitialReward: 100, HalvingInterval: 10}}
+
+	rewards := map[int]uint64{0: 100, 9: 100, 10: 50, 19: 50, 20: 25, 30: 12, 60: 1, 70: 0, 1000000: 0}
+	for blockNum, expected := range rewards {
+		if reward := params.ComputeBlockReward(blockNum); reward != expected {
+			t.Fatalf(`Expected reward %v at block %v, got %v`, expected, blockNum, reward)
+		}
+	}
+
+	var total uint64 = 0
+	for blockNum := 0; blockNum < 100; blockNum++ {
+		total += params.ComputeBlockReward(blockNum)
+		if supply := params.ComputeIssuedSupply(blockNum); supply != total {
+			t.Fatalf(`Expected issued supply %v at block %v, got %v`, total, blockNum, supply)
+		}
+	}
+
+	// 10 blocks each of 100, 50, 25, 12, 6, 3 and 1
+	if supply := params.ComputeIssuedSupply(1000000); supply != 1970 {
+		t.Fatalf(`Expected final supply of 1970, got %v`, supply)
+	}
+}
+
+// Tests that the reward never drops below the terminal reward
+func TestTerminalReward(t *testing.T) {
+	params := Params{Emission: EmissionSchedule{InitialReward: 100, HalvingInterval: 10, TerminalReward: 20}}
+
+	if reward := params.ComputeBlockReward(20); reward != 25 {
+		t.Fatalf(`Expected reward 25 before the terminal reward, got %v`, reward)
+	}
+	if reward := params.ComputeBlockReward(1000000); reward != 20 {
+		t.Fatalf(`Expected terminal reward 20, got %v`, reward)
+	}
+
+	expected := uint64(10*100 + 10*50 + 10*25 + (1000000-29)*20)
+	if supply := params.ComputeIssuedSupply(1000000); supply != expected {
+		t.Fatalf(`Expected issued supply %v, got %v`, expected, supply)
+	}
+}
+
+// Tests that rewards stop once the supply cap is reached
+func TestMaxSupply(t *testing.T) {
+	params := Params{Emission: EmissionSchedule{InitialReward: 100, TerminalReward: 100, MaxSupply: 250}}
+
+	rewards := []uint64{100, 100, 50, 0}
+	for blockNum, expected := range rewards {
+		if reward := params.ComputeBlockReward(blockNum); reward != expected {
+			t.Fatalf(`Expected reward %v at block %v, got %v`, expected, blockNum, reward)
+		}
+	}
+
+	if supply := params.ComputeIssuedSupply(1000000); supply != 250 {
+		t.Fatalf(`Expected issued supply to stop at the cap of 250, got %v`, supply)
+	}
+}
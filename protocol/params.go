@@ -0,0 +1,59 @@
+package protocol
+
+import (
+	"github.com/AndrewCLu/TestcoinNode/common"
+)
+
+// Params are the parameters that distinguish one Testcoin network from another
+type Params struct {
+	Name             string // The name of the network
+	AddressPrefix    string // The human readable prefix of encoded addresses on the network
+	CoinbaseMaturity int    // The number of confirmations a coinbase output needs before it can be spent
+	Emission         EmissionSchedule
+	SnapshotHashes   map[int]common.Hash // Content hashes of trusted utxo snapshots, by the block number they were taken at
+}
+
+// The parameters of the main Testcoin network
+var MainNetParams = Params{
+	Name:             "mainnet",
+	AddressPrefix:    "tc",
+	CoinbaseMaturity: 100,
+	Emission:         DefaultEmissionSchedule,
+}
+
+// The parameters of the public test network
+var TestNetParams = Params{
+	Name:             "testnet",
+	AddressPrefix:    "tct",
+	CoinbaseMaturity: 10,
+	Emission:         DefaultEmissionSchedule,
+}
+
+// The parameters of a private development network, where coinbase outputs can be spent immediately
+var DevNetParams = Params{
+	Name:             "devnet",
+	AddressPrefix:    "tcd",
+	CoinbaseMaturity: 0,
+	Emission:         DefaultEmissionSchedule,
+}
+
+// Returns true if a coinbase output created in the block at coinbaseBlockNum can be spent in the block at spendBlockNum
+func (params *Params) IsCoinbaseMature(coinbaseBlockNum int, spendBlockNum int) bool {
+	return spendBlockNum-coinbaseBlockNum >= params.CoinbaseMaturity
+}
+
+// Returns true if hash is the pinned content hash of a utxo snapshot taken at blockNum
+func (params *Params) IsSnapshotTrusted(blockNum int, hash common.Hash) bool {
+	pinned, ok := params.SnapshotHashes[blockNum]
+	return ok && pinned.Equal(hash)
+}
+
+// Encodes an address into its human readable form on this network
+func (params *Params) EncodeAddress(address common.Address) string {
+	return address.Encode(params.AddressPrefix)
+}
+
+// Decodes a human readable address, rejecting addresses that are corrupted or belong to another network
+func (params *Params) DecodeAddress(encoded string) (common.Address, error) {
+	return common.DecodeAddress(encoded, params.AddressPrefix)
+}
@@ -4,20 +4,20 @@ import (
 	"github.com/AndrewCLu/TestcoinNode/common"
 )
 
-const ProtocolVersionLength = 2  // Number of bytes used to denote the protocol version
-const CurrentProtocolVersion = 1 // Current protocol version
+const ProtocolVersionLength = 2         // Number of bytes used to denote the protocol version
+const CurrentProtocolVersion = 3        // Current protocol version
+const MerkleTreeProtocolVersion = 2     // First protocol version where blocks commit to their transactions with a Merkle tree
+const FixedSignatureProtocolVersion = 3 // First protocol version where input signatures are fixed width and prefixed with their length
 
 const TestcoinUnitMultiplier = 1000000000 // Actual account values are 1000000000 times less than the transaction amount values
 
-const MaxTransactionsInBlock = 2 // How many transactions are allowed in each block
+const MaxOutputValue = 100 * 1000 * 1000 * TestcoinUnitMultiplier // The largest amount a single transaction output can hold
+
+const MaxBlockSize = 1000    // The maximum size of a serialized block in bytes, including its header and coinbase
+const MinRelayFeeRate = 1000 // The minimum fee rate, in base units per byte, for a transaction to enter the pending pool
 
 // Given the current block number, returns the appropriate target for solving proof of work
 func ComputeTarget(blockNumber int) common.Target {
 	// return [4]byte{0, 0, 0, 15}
 	return [4]byte{0, 255, 255, 255}
 }
-
-// Given the current block number, return the appropriate coinbase reward for mining a block
-func ComputeBlockReward(blockNumber int) uint64 {
-	return 10 * TestcoinUnitMultiplier
-}
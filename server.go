@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/AndrewCLu/TestcoinNode/node"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+	"github.com/AndrewCLu/TestcoinNode/util"
 )
 
 // import (
@@ -69,45 +71,60 @@ import (
 
 func main() {
 	fmt.Println("\n\n\n-------------------BEGINNING TEST-------------------")
-	node, _ := node.New()
+	node, _ := node.New(&protocol.DevNetParams)
 
 	satoshi := node.NewAccount()
-	node.Initialize(satoshi.Address)
-	node.BeginMiner(satoshi.Address)
-
 	alice := node.NewAccount()
 	bob := node.NewAccount()
 
+	// Accounts are addressed by their human readable form, which is checked against the network
+	satoshiAddress := node.EncodeAddress(satoshi.Address)
+	aliceAddress := node.EncodeAddress(alice.Address)
+	bobAddress := node.EncodeAddress(bob.Address)
+
+	node.Initialize(satoshiAddress)
+	node.BeginMiner(satoshiAddress)
+
 	fmt.Println("Creating the blockchain...")
 	node.PrintChainState()
 
-	node.NewPeerTransaction(satoshi, bob.Address, 4.1, 2)
-	node.NewPeerTransaction(satoshi, bob.Address, 4.2, 2.4)
-	node.NewPeerTransaction(satoshi, bob.Address, 4.3, 2.3)
-	node.NewPeerTransaction(satoshi, bob.Address, 4.4, 2.2)
-	node.NewPeerTransaction(satoshi, bob.Address, 4.5, 2.1)
-	node.NewPeerTransaction(satoshi, bob.Address, 4.6, 2.05)
+	node.NewPeerTransaction(satoshi, bobAddress, util.MustParseAmount("4.1"), util.MustParseAmount("2"), nil)
+	node.NewPeerTransaction(satoshi, bobAddress, util.MustParseAmount("4.2"), util.MustParseAmount("2.4"), nil)
+	node.NewPeerTransaction(satoshi, bobAddress, util.MustParseAmount("4.3"), util.MustParseAmount("2.3"), nil)
+	node.NewPeerTransaction(satoshi, bobAddress, util.MustParseAmount("4.4"), util.MustParseAmount("2.2"), nil)
+	node.NewPeerTransaction(satoshi, bobAddress, util.MustParseAmount("4.5"), util.MustParseAmount("2.1"), nil)
+	node.NewPeerTransaction(satoshi, bobAddress, util.MustParseAmount("4.6"), util.MustParseAmount("2.05"), nil)
 	node.MineBlock()
 
 	fmt.Println("Distributing the wealth to bob...")
 	node.PrintChainState()
 
-	node.NewPeerTransaction(satoshi, alice.Address, 3, 1)
+	node.NewPeerTransaction(satoshi, aliceAddress, util.MustParseAmount("3"), util.MustParseAmount("1"), nil)
 	node.MineBlock()
 
 	fmt.Println("Sending to alice...")
 	node.PrintChainState()
 
-	node.NewPeerTransaction(alice, bob.Address, .5, .25)
+	node.NewPeerTransaction(alice, bobAddress, util.MustParseAmount(".5"), util.MustParseAmount(".25"), nil)
 	node.MineBlock()
 
 	fmt.Println("Alice owes bob...")
 	node.PrintChainState()
 
-	node.NewPeerTransaction(alice, bob.Address, 4.5, .25)
-	node.NewPeerTransaction(alice, bob.Address, .5, 3.25)
+	node.NewPeerTransaction(alice, bobAddress, util.MustParseAmount("4.5"), util.MustParseAmount(".25"), nil)
+	node.NewPeerTransaction(alice, bobAddress, util.MustParseAmount(".5"), util.MustParseAmount("3.25"), nil)
 	node.MineBlock()
 
 	fmt.Println("Testing invalid transactions")
 	node.PrintChainState()
+
+	node.NewPeerTransactionWithFeeTarget(bob, aliceAddress, util.MustParseAmount("1"), 2, nil)
+	node.MineBlock()
+
+	fmt.Println("Bob pays an estimated fee...")
+	node.PrintChainState()
+
+	fmt.Println("Checking the coin supply...")
+	fmt.Printf("%v coins have been issued\n", node.GetReadableIssuedSupply())
+	fmt.Printf("%v coins are in circulation\n", node.GetReadableCirculatingSupply())
 }
@@ -73,6 +73,7 @@ func New(
 }
 
 // Takes a transaction and returns a byte array representing the transaction
+// Input signatures are encoded as required by the transaction's protocol version, since the bytes determine its hash and size
 func (t *Transaction) Bytes() []byte {
 	versionBytes := util.Uint16ToBytes(t.ProtocolVersion)
 
@@ -80,7 +81,7 @@ func (t *Transaction) Bytes() []byte {
 
 	inputBytes := make([]byte, 0)
 	for _, input := range t.Inputs {
-		inputBytes = append(inputBytes, input.Bytes()...)
+		inputBytes = append(inputBytes, input.bytes(t.ProtocolVersion)...)
 	}
 
 	numOutputBytes := util.Uint16ToBytes(uint16(len(t.Outputs)))
@@ -108,40 +109,69 @@ func (t *Transaction) Bytes() []byte {
 	return util.ConcatByteSlices(allBytes)
 }
 
+// Returns the size of a transaction in bytes, which is used to compute its fee rate
+func (t *Transaction) Size() int {
+	return len(t.Bytes())
+}
+
 // Convertes a byte array back into a Transaction
-// TODO: Error handling for out of bounds
+// Returns nil if the bytes are not a well formed transaction
+// Inputs from before FixedSignatureProtocolVersion do not record where their signature ends, so only their coinbases can be decoded
 func BytesToTransaction(bytes []byte) *Transaction {
 	currentByte := 0
 
+	if len(bytes) < protocol.ProtocolVersionLength+NumInputOutputLength {
+		return nil
+	}
 	protocolVersion := util.BytesToUint16(bytes[currentByte : currentByte+protocol.ProtocolVersionLength])
 	currentByte += protocol.ProtocolVersionLength
 
 	numInputs := int(util.BytesToUint16(bytes[currentByte : currentByte+NumInputOutputLength]))
 	currentByte += NumInputOutputLength
+	if numInputs > 0 && protocolVersion < protocol.FixedSignatureProtocolVersion {
+		return nil
+	}
 
 	inputs := []*TransactionInput{}
 	for i := 0; i < numInputs; i += 1 {
 		verificationOffset := currentByte + TransactionOutputPointerLength
+		if len(bytes) < verificationOffset+TransactionVerificationLengthLength {
+			return nil
+		}
 		verificationLength := util.BytesToUint16(bytes[verificationOffset : verificationOffset+TransactionVerificationLengthLength])
 
 		inputLength := TransactionOutputPointerLength + TransactionVerificationLengthLength + int(verificationLength)
+		if len(bytes) < currentByte+inputLength {
+			return nil
+		}
 		input := BytesToTransactionInput(bytes[currentByte : currentByte+inputLength])
+		if input == nil {
+			return nil
+		}
 		inputs = append(inputs, input)
 		currentByte += inputLength
 	}
 
+	if len(bytes) < currentByte+NumInputOutputLength {
+		return nil
+	}
 	numOutputs := int(util.BytesToUint16(bytes[currentByte : currentByte+NumInputOutputLength]))
 	currentByte += NumInputOutputLength
 
 	outputs := []*TransactionOutput{}
 	for i := 0; i < numOutputs; i += 1 {
+		if len(bytes) < currentByte+TransactionOutputLength {
+			return nil
+		}
 		output := BytesToTransactionOutput(bytes[currentByte : currentByte+TransactionOutputLength])
 		outputs = append(outputs, output)
 		currentByte += TransactionOutputLength
 	}
 
 	timestamp := new(time.Time)
-	timestamp.UnmarshalBinary(bytes[currentByte:])
+	if err := timestamp.UnmarshalBinary(bytes[currentByte:]); err != nil {
+		return nil
+	}
 
 	return &Transaction{
 		ProtocolVersion: protocolVersion,
@@ -151,13 +181,17 @@ func BytesToTransaction(bytes []byte) *Transaction {
 	}
 }
 
-// Converts a TransactionInput into a byte array
+// Converts a TransactionInput into a byte array using the current protocol version
 func (t *TransactionInput) Bytes() []byte {
+	return t.bytes(protocol.CurrentProtocolVersion)
+}
+
+func (t *TransactionInput) bytes(protocolVersion uint16) []byte {
 	outputPointerBytes := t.OutputPointer.Bytes()
 
 	verificationLengthBytes := util.Uint16ToBytes(t.VerificationLength)
 
-	verificationBytes := t.Verification.Bytes()
+	verificationBytes := t.Verification.bytes(protocolVersion)
 
 	allBytes := [][]byte{
 		outputPointerBytes,
@@ -169,10 +203,14 @@ func (t *TransactionInput) Bytes() []byte {
 }
 
 // Coverts a byte array into a TransactionInput
-// TODO: Error handling for out of bounds
+// Returns nil if the bytes are not a well formed input
 func BytesToTransactionInput(bytes []byte) *TransactionInput {
 	currentByte := 0
 
+	if len(bytes) < TransactionOutputPointerLength+TransactionVerificationLengthLength {
+		return nil
+	}
+
 	outputPointerBytes := bytes[currentByte : currentByte+TransactionOutputPointerLength]
 	currentByte += TransactionOutputPointerLength
 
@@ -184,6 +222,9 @@ func BytesToTransactionInput(bytes []byte) *TransactionInput {
 	outputPointer := BytesToTransactionOutputPointer(outputPointerBytes)
 	verificationLength := util.BytesToUint16(verificationLengthBytes)
 	verification := BytesToTransactionInputVerification(verificationBytes)
+	if verification == nil || int(verificationLength) != len(verificationBytes) {
+		return nil
+	}
 
 	input := TransactionInput{
 		OutputPointer:      outputPointer,
@@ -225,13 +266,25 @@ func BytesToTransactionOutputPointer(bytes []byte) *TransactionOutputPointer {
 	return &ptr
 }
 
-// Converts a TransactionInputVerification to bytes
+// Converts a TransactionInputVerification to bytes using the current protocol version
 func (t *TransactionInputVerification) Bytes() []byte {
-	signatureBytes := t.Signature.Bytes()
+	return t.bytes(protocol.CurrentProtocolVersion)
+}
 
+// Earlier protocol versions wrote the signature at its minimal width with no length prefix
+func (t *TransactionInputVerification) bytes(protocolVersion uint16) []byte {
 	publicKeyBytes := t.EncodedPublicKey
 
+	if protocolVersion < protocol.FixedSignatureProtocolVersion {
+		return util.ConcatByteSlices([][]byte{t.Signature.LegacyBytes(), publicKeyBytes})
+	}
+
+	signatureBytes := t.Signature.Bytes()
+
+	signatureLengthBytes := util.Uint16ToBytes(uint16(len(signatureBytes)))
+
 	allBytes := [][]byte{
+		signatureLengthBytes,
 		signatureBytes,
 		publicKeyBytes,
 	}
@@ -240,13 +293,20 @@ func (t *TransactionInputVerification) Bytes() []byte {
 }
 
 // Converts bytes to a TransactionInputVerification
-// TODO: Error handling for out of bounds
+// Returns nil if the bytes are too short for the signature they describe
 func BytesToTransactionInputVerification(bytes []byte) *TransactionInputVerification {
 	currentByte := 0
+	if len(bytes) < TransactionSignatureLengthLength {
+		return nil
+	}
 	signatureLengthBytes := bytes[currentByte : currentByte+TransactionSignatureLengthLength]
 	signatureLength := int(util.BytesToUint16(signatureLengthBytes))
 	currentByte += TransactionSignatureLengthLength
 
+	if len(bytes) < currentByte+signatureLength {
+		return nil
+	}
+
 	signatureBytes := bytes[currentByte : currentByte+signatureLength]
 	signature := crypto.BytesToECDSASignature(signatureBytes)
 	currentByte += signatureLength
@@ -254,6 +314,7 @@ func BytesToTransactionInputVerification(bytes []byte) *TransactionInputVerifica
 	publicKey := bytes[currentByte:]
 
 	verification := TransactionInputVerification{
+		SignatureLength:  uint16(signatureLength),
 		Signature:        signature,
 		EncodedPublicKey: publicKey,
 	}
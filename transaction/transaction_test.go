@@ -1,16 +1,19 @@
 package transaction
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/protocol"
 	"github.com/AndrewCLu/TestcoinNode/util"
 )
 
 // Tests that converting a transaction into a byte array and back yields the same transaction
 func TestTransactionToByteArray(t *testing.T) {
 	address := [common.AddressLength]byte{2, 3}
-	amount := util.Float64UnitToUnit64Unit(69.69)
+	amount := uint64(util.MustParseAmount("69.69"))
 
 	output := TransactionOutput{ReceiverAddress: address, Amount: amount}
 	transaction, success := New(
@@ -30,3 +33,73 @@ func TestTransactionToByteArray(t *testing.T) {
 		t.Fatalf(`Decoded transaction is not equal to original. Original: %v, Decoded: %v`, transaction, decodedTransaction)
 	}
 }
+
+// Tests that a transaction with signed inputs survives conversion to bytes and back
+func TestSignedTransactionToByteArray(t *testing.T) {
+	publicKey, privateKey, _ := crypto.NewDigitalSignatureKeys()
+	inputs := []*TransactionInput{}
+	for i := 0; i < 3; i++ {
+		ptr := &TransactionOutputPointer{TransactionHash: crypto.HashBytes([]byte{byte(i)}), OutputIndex: uint16(i)}
+		signature, _ := crypto.SignByteArray(ptr.Bytes(), privateKey)
+		verification := &TransactionInputVerification{Signature: signature, EncodedPublicKey: publicKey}
+		inputs = append(inputs, &TransactionInput{
+			OutputPointer:      ptr,
+			VerificationLength: uint16(len(verification.Bytes())),
+			Verification:       verification,
+		})
+	}
+	output := &TransactionOutput{ReceiverAddress: common.Address{1}, Amount: 1}
+	transaction, _ := New(inputs, []*TransactionOutput{output})
+
+	transactionBytes := transaction.Bytes()
+	decodedTransaction := BytesToTransaction(transactionBytes)
+	if decodedTransaction == nil || !transaction.Equal(decodedTransaction) {
+		t.Fatalf(`Decoded signed transaction is not equal to original`)
+	}
+	for i, input := range decodedTransaction.Inputs {
+		if !crypto.VerifyByteArray(input.OutputPointer.Bytes(), input.Verification.EncodedPublicKey, input.Verification.Signature) {
+			t.Fatalf(`Signature of decoded input %v does not verify`, i)
+		}
+	}
+
+	if BytesToTransaction(transactionBytes[:len(transactionBytes)/2]) != nil {
+		t.Fatalf(`Decoded a truncated transaction`)
+	}
+}
+
+// Tests that transactions from before fixed width signatures keep their original encoding, and with it their hash and size
+func TestLegacySignatureEncoding(t *testing.T) {
+	publicKey, privateKey, _ := crypto.NewDigitalSignatureKeys()
+	ptr := &TransactionOutputPointer{TransactionHash: crypto.HashBytes([]byte{1}), OutputIndex: 1}
+	signature, _ := crypto.SignByteArray(ptr.Bytes(), privateKey)
+	verification := &TransactionInputVerification{Signature: signature, EncodedPublicKey: publicKey}
+	legacyVerification := append(signature.LegacyBytes(), publicKey...)
+	input := &TransactionInput{
+		OutputPointer:      ptr,
+		VerificationLength: uint16(len(legacyVerification)),
+		Verification:       verification,
+	}
+	output := &TransactionOutput{ReceiverAddress: common.Address{1}, Amount: 1}
+	transaction, _ := New([]*TransactionInput{input}, []*TransactionOutput{output})
+	transaction.ProtocolVersion = protocol.FixedSignatureProtocolVersion - 1
+
+	timeBytes, _ := transaction.Timestamp.MarshalBinary()
+	expected := util.ConcatByteSlices([][]byte{
+		util.Uint16ToBytes(transaction.ProtocolVersion),
+		util.Uint16ToBytes(1),
+		ptr.Bytes(),
+		util.Uint16ToBytes(input.VerificationLength),
+		legacyVerification,
+		util.Uint16ToBytes(1),
+		output.Bytes(),
+		timeBytes,
+	})
+	if !bytes.Equal(transaction.Bytes(), expected) || transaction.Size() != len(expected) {
+		t.Fatalf(`Legacy transaction was not encoded with minimal width signatures and no signature length`)
+	}
+
+	// The split between r and s is lost, so legacy inputs are refused rather than decoded into a wrong signature
+	if BytesToTransaction(transaction.Bytes()) != nil {
+		t.Fatalf(`Decoded a legacy transaction with inputs`)
+	}
+}
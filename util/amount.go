@@ -0,0 +1,142 @@
+package util
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewCLu/TestcoinNode/protocol"
+)
+
+const AmountDecimals = 9 // The number of decimal places in a human readable amount, matching TestcoinUnitMultiplier
+
+const MaxAmount = Amount(math.MaxUint64) // The largest representable amount
+
+var (
+	ErrAmountSyntax    = errors.New("amount is not a non-negative decimal number")
+	ErrAmountPrecision = errors.New("amount has more than 9 decimal places")
+	ErrAmountOverflow  = errors.New("amount is too large")
+)
+
+// An amount is a quantity of Testcoin counted exactly in base units
+// One coin is TestcoinUnitMultiplier base units
+type Amount uint64
+
+// Returns the amount worth a whole number of coins
+// Returns a bool indicating success, which fails if the amount overflows
+func NewAmountFromCoins(coins uint64) (Amount, bool) {
+	return Amount(coins).Mul(protocol.TestcoinUnitMultiplier)
+}
+
+// Parses a human readable decimal number of coins such as "4.1" into an exact amount
+func ParseAmount(s string) (Amount, error) {
+	whole, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		whole, fraction = s[:i], s[i+1:]
+	}
+
+	if whole == "" && fraction == "" {
+		return 0, ErrAmountSyntax
+	}
+	if !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrAmountSyntax
+	}
+	if len(fraction) > AmountDecimals {
+		return 0, ErrAmountPrecision
+	}
+
+	var coins uint64 = 0
+	if whole != "" {
+		var err error
+		coins, err = strconv.ParseUint(whole, 10, 64)
+		if err != nil {
+			return 0, ErrAmountOverflow
+		}
+	}
+
+	var units uint64 = 0
+	if fraction != "" {
+		fraction += strings.Repeat("0", AmountDecimals-len(fraction))
+		units, _ = strconv.ParseUint(fraction, 10, 64)
+	}
+
+	amount, ok := NewAmountFromCoins(coins)
+	if !ok {
+		return 0, ErrAmountOverflow
+	}
+	amount, ok = amount.Add(Amount(units))
+	if !ok {
+		return 0, ErrAmountOverflow
+	}
+
+	return amount, nil
+}
+
+// Parses a human readable amount, panicking if it is invalid
+// Only intended for amounts written in code, such as in tests
+func MustParseAmount(s string) Amount {
+	amount, err := ParseAmount(s)
+	if err != nil {
+		panic("invalid amount " + s + ": " + err.Error())
+	}
+
+	return amount
+}
+
+// Formats an amount as a human readable decimal number of coins, without trailing zeros
+func (a Amount) String() string {
+	coins := uint64(a) / protocol.TestcoinUnitMultiplier
+	units := uint64(a) % protocol.TestcoinUnitMultiplier
+
+	s := strconv.FormatUint(coins, 10)
+	if units == 0 {
+		return s
+	}
+
+	fraction := strconv.FormatUint(units, 10)
+	fraction = strings.Repeat("0", AmountDecimals-len(fraction)) + fraction
+
+	return s + "." + strings.TrimRight(fraction, "0")
+}
+
+// Returns the sum of two amounts
+// Returns a bool indicating success, which fails if the sum overflows
+func (a Amount) Add(b Amount) (Amount, bool) {
+	if a > MaxAmount-b {
+		return 0, false
+	}
+
+	return a + b, true
+}
+
+// Returns the difference of two amounts
+// Returns a bool indicating success, which fails if b is larger than a
+func (a Amount) Sub(b Amount) (Amount, bool) {
+	if b > a {
+		return 0, false
+	}
+
+	return a - b, true
+}
+
+// Returns the amount multiplied by n
+// Returns a bool indicating success, which fails if the product overflows
+func (a Amount) Mul(n uint64) (Amount, bool) {
+	if n != 0 && uint64(a) > uint64(MaxAmount)/n {
+		return 0, false
+	}
+
+	return Amount(uint64(a) * n), true
+}
+
+// Returns true if every character of s is a decimal digit
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
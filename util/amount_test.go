@@ -0,0 +1,80 @@
+package util
+
+import (
+	"testing"
+)
+
+// Tests that decimal strings parse exactly and format back to the same string
+func TestParseFormatAmount(t *testing.T) {
+	amounts := map[string]Amount{
+		"0":                     0,
+		"4.1":                   4100000000,
+		"0.000000001":           1,
+		"10":                    10000000000,
+		"69.69":                 69690000000,
+		"18446744073.709551615": MaxAmount,
+	}
+
+	for s, expected := range amounts {
+		amount, err := ParseAmount(s)
+		if err != nil || amount != expected {
+			t.Fatalf(`Failed to parse %v. Expected: %v, Parsed: %v, Error: %v`, s, uint64(expected), uint64(amount), err)
+		}
+		if amount.String() != s {
+			t.Fatalf(`Formatted amount does not match. Original: %v, Formatted: %v`, s, amount.String())
+		}
+	}
+
+	// Equivalent forms parse to the same amount
+	for _, s := range []string{"4.10", ".5", "5.", "007"} {
+		if _, err := ParseAmount(s); err != nil {
+			t.Fatalf(`Failed to parse %v: %v`, s, err)
+		}
+	}
+}
+
+// Tests that invalid, overly precise or overflowing amounts are rejected
+func TestParseInvalidAmount(t *testing.T) {
+	invalid := map[string]error{
+		"":                        ErrAmountSyntax,
+		".":                       ErrAmountSyntax,
+		"-1":                      ErrAmountSyntax,
+		"1e9":                     ErrAmountSyntax,
+		"1.2.3":                   ErrAmountSyntax,
+		" 1":                      ErrAmountSyntax,
+		"0.0000000001":            ErrAmountPrecision,
+		"18446744073.709551616":   ErrAmountOverflow,
+		"18446744074":             ErrAmountOverflow,
+		"99999999999999999999999": ErrAmountOverflow,
+	}
+
+	for s, expected := range invalid {
+		if _, err := ParseAmount(s); err != expected {
+			t.Fatalf(`Expected error %v parsing %v, got %v`, expected, s, err)
+		}
+	}
+}
+
+// Tests that arithmetic reports overflow and underflow instead of wrapping
+func TestAmountArithmetic(t *testing.T) {
+	if sum, ok := MustParseAmount("4.1").Add(MustParseAmount("0.9")); !ok || sum != MustParseAmount("5") {
+		t.Fatalf(`Expected 4.1 + 0.9 = 5, got %v`, sum)
+	}
+	if _, ok := MaxAmount.Add(1); ok {
+		t.Fatalf(`Adding to the maximum amount did not overflow`)
+	}
+
+	if difference, ok := MustParseAmount("5").Sub(MustParseAmount("0.1")); !ok || difference != MustParseAmount("4.9") {
+		t.Fatalf(`Expected 5 - 0.1 = 4.9, got %v`, difference)
+	}
+	if _, ok := MustParseAmount("1").Sub(MustParseAmount("2")); ok {
+		t.Fatalf(`Subtracting a larger amount did not fail`)
+	}
+
+	if product, ok := MustParseAmount("2.5").Mul(4); !ok || product != MustParseAmount("10") {
+		t.Fatalf(`Expected 2.5 * 4 = 10, got %v`, product)
+	}
+	if _, ok := MaxAmount.Mul(2); ok {
+		t.Fatalf(`Multiplying the maximum amount did not overflow`)
+	}
+}
@@ -3,8 +3,6 @@ package util
 import (
 	"encoding/binary"
 	"math"
-
-	"github.com/AndrewCLu/TestcoinNode/protocol"
 )
 
 // Concatenates a slice of byte slices into one slice
@@ -24,18 +22,6 @@ func ConcatByteSlices(slices [][]byte) []byte {
 	return allBytes
 }
 
-// TODO: Handle special cases where values are very large
-// Converts a human readable float representation of a Testcoin amount into a transactional amount
-func Float64UnitToUnit64Unit(value float64) uint64 {
-	return uint64(value * protocol.TestcoinUnitMultiplier)
-}
-
-// TODO: Handle special cases where values are very large
-// Converts a uin64 transactional representation of a Testcoin amount innto human readable float units
-func Uint64UnitToFloat64Unit(value uint64) float64 {
-	return float64(value) / protocol.TestcoinUnitMultiplier
-}
-
 // Converts a uint16 to byte representation
 func Uint16ToBytes(value uint16) []byte {
 	bytes := make([]byte, 2)
@@ -0,0 +1,272 @@
+package wallet
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+const DefaultBranchAndBoundTries = 100000 // The number of branches branch and bound explores before giving up
+
+// A coin is an unspent transaction output that the wallet can spend
+type Coin struct {
+	OutputPointer *transaction.TransactionOutputPointer
+	Amount        uint64
+}
+
+// Coin selection params describe the amount a selection must cover and what each input and change output cost
+type CoinSelectionParams struct {
+	Target       uint64 // The amount the selected coins must cover, including the transaction fee
+	CostPerInput uint64 // The fee paid for adding one more input to the transaction
+	CostOfChange uint64 // The fee paid for creating a change output and later spending it
+}
+
+// Returns selection params for covering target at a fee rate in base units per byte
+// Each input is charged for its size, and change for the size of its output and of the input that later spends it
+func NewCoinSelectionParams(target uint64, feeRate uint64) *CoinSelectionParams {
+	return &CoinSelectionParams{
+		Target:       target,
+		CostPerInput: FeeForSize(feeRate, EstimatedInputSize),
+		CostOfChange: FeeForSize(feeRate, EstimatedOutputSize+EstimatedInputSize),
+	}
+}
+
+// A coin selector chooses which coins to spend to cover a target amount
+type CoinSelector interface {
+	// Returns coins whose total value, after paying for each input, covers the target
+	// Returns a bool indicating success, which fails if no selection covers the target
+	SelectCoins(coins []*Coin, params *CoinSelectionParams) ([]*Coin, bool)
+}
+
+// Returns the coin selector used when a send does not specify one
+// Looks for a selection that needs no change output, falling back to spending the largest coins first
+func DefaultCoinSelector() CoinSelector {
+	return &BranchAndBound{
+		MaxTries: DefaultBranchAndBoundTries,
+		Fallback: &LargestFirst{},
+	}
+}
+
+// Largest first spends the largest coins first, minimizing the number of inputs
+type LargestFirst struct{}
+
+func (s *LargestFirst) SelectCoins(coins []*Coin, params *CoinSelectionParams) ([]*Coin, bool) {
+	sorted := sortCoins(coins, params, true)
+	return selectInOrder(sorted, params)
+}
+
+// Smallest first spends the smallest coins first, consolidating dust into fewer outputs
+type SmallestFirst struct{}
+
+func (s *SmallestFirst) SelectCoins(coins []*Coin, params *CoinSelectionParams) ([]*Coin, bool) {
+	sorted := sortCoins(coins, params, false)
+	return selectInOrder(sorted, params)
+}
+
+// Branch and bound searches for a selection that exceeds the target by no more than the cost of change,
+// so that the transaction needs no change output
+// If no such selection is found, the fallback selector is used if one is set
+type BranchAndBound struct {
+	MaxTries int          // The number of branches to explore before giving up
+	Fallback CoinSelector // The selector to use if no changeless selection is found, may be nil
+}
+
+func (s *BranchAndBound) SelectCoins(coins []*Coin, params *CoinSelectionParams) ([]*Coin, bool) {
+	selected, ok := s.search(coins, params)
+	if ok {
+		return selected, true
+	}
+
+	if s.Fallback != nil {
+		return s.Fallback.SelectCoins(coins, params)
+	}
+
+	return nil, false
+}
+
+// Performs a depth first search over including or excluding each coin, largest first,
+// keeping the selection with the least excess over the target
+func (s *BranchAndBound) search(coins []*Coin, params *CoinSelectionParams) ([]*Coin, bool) {
+	sorted := sortCoins(coins, params, true)
+	values := make([]uint64, len(sorted))
+	var available uint64 = 0
+	for i, coin := range sorted {
+		values[i] = effectiveValue(coin, params)
+		available += values[i]
+	}
+
+	if available < params.Target {
+		return nil, false
+	}
+	upperBound := params.Target + params.CostOfChange
+
+	var bestExcess uint64 = 0
+	var best []bool
+	included := make([]bool, len(sorted))
+	tries := 0
+
+	// remaining is the total value of coins that have not been decided yet
+	var explore func(depth int, total uint64, remaining uint64)
+	explore = func(depth int, total uint64, remaining uint64) {
+		tries++
+		if tries > s.MaxTries {
+			return
+		}
+
+		// Prune branches that overshoot, cannot reach the target, or cannot beat the best solution
+		if total > upperBound || total+remaining < params.Target {
+			return
+		}
+		if best != nil && total >= params.Target && total-params.Target >= bestExcess {
+			return
+		}
+
+		if total >= params.Target {
+			best = append([]bool{}, included...)
+			bestExcess = total - params.Target
+			return
+		}
+
+		if depth == len(sorted) {
+			return
+		}
+
+		remaining -= values[depth]
+
+		// Including a coin equal to the previously excluded one explores an identical branch
+		if depth == 0 || included[depth-1] || values[depth] != values[depth-1] {
+			included[depth] = true
+			explore(depth+1, total+values[depth], remaining)
+			included[depth] = false
+		}
+
+		explore(depth+1, total, remaining)
+	}
+	explore(0, 0, available)
+
+	if best == nil {
+		return nil, false
+	}
+
+	selected := []*Coin{}
+	for i, isIncluded := range best {
+		if isIncluded {
+			selected = append(selected, sorted[i])
+		}
+	}
+
+	return selected, true
+}
+
+// Random improve randomly selects coins until the target is covered, then keeps adding random coins
+// while they bring the total closer to twice the target, leaving change similar in size to the payment
+// This avoids revealing which output is the payment and keeps a healthy spread of coin sizes
+type RandomImprove struct {
+	Rand *rand.Rand // The source of randomness, seeded from the clock if nil
+}
+
+func (s *RandomImprove) SelectCoins(coins []*Coin, params *CoinSelectionParams) ([]*Coin, bool) {
+	r := s.Rand
+	if r == nil {
+		r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	candidates := sortCoins(coins, params, true)
+	r.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	// Randomly select coins until the target is covered
+	selected := []*Coin{}
+	var total uint64 = 0
+	next := 0
+	for ; next < len(candidates) && total < params.Target; next++ {
+		selected = append(selected, candidates[next])
+		total += effectiveValue(candidates[next], params)
+	}
+	if total < params.Target {
+		return nil, false
+	}
+
+	// Improve the selection by moving the total towards twice the target without exceeding three times it
+	ideal := 2 * params.Target
+	limit := 3 * params.Target
+	for ; next < len(candidates); next++ {
+		newTotal := total + effectiveValue(candidates[next], params)
+		if newTotal > limit || distance(newTotal, ideal) >= distance(total, ideal) {
+			continue
+		}
+
+		selected = append(selected, candidates[next])
+		total = newTotal
+	}
+
+	return selected, true
+}
+
+// Selects coins in the given order until the target is covered
+func selectInOrder(coins []*Coin, params *CoinSelectionParams) ([]*Coin, bool) {
+	selected := []*Coin{}
+	var total uint64 = 0
+	for _, coin := range coins {
+		if total >= params.Target {
+			break
+		}
+
+		selected = append(selected, coin)
+		total += effectiveValue(coin, params)
+	}
+
+	if total < params.Target {
+		return nil, false
+	}
+
+	return selected, true
+}
+
+// Returns the coins worth spending sorted by amount, dropping those that cost more to spend than they are worth
+// Coins with equal amounts are ordered by output pointer so selections are deterministic
+func sortCoins(coins []*Coin, params *CoinSelectionParams, descending bool) []*Coin {
+	sorted := []*Coin{}
+	for _, coin := range coins {
+		if effectiveValue(coin, params) > 0 {
+			sorted = append(sorted, coin)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Amount != sorted[j].Amount {
+			return (sorted[i].Amount > sorted[j].Amount) == descending
+		}
+
+		hashI := sorted[i].OutputPointer.TransactionHash.Hex()
+		hashJ := sorted[j].OutputPointer.TransactionHash.Hex()
+		if hashI != hashJ {
+			return hashI < hashJ
+		}
+
+		return sorted[i].OutputPointer.OutputIndex < sorted[j].OutputPointer.OutputIndex
+	})
+
+	return sorted
+}
+
+// Returns the value a coin contributes to a selection after paying for its input
+func effectiveValue(coin *Coin, params *CoinSelectionParams) uint64 {
+	if coin.Amount <= params.CostPerInput {
+		return 0
+	}
+
+	return coin.Amount - params.CostPerInput
+}
+
+// Returns the absolute difference between two amounts
+func distance(a uint64, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/transaction"
+)
+
+// Creates a synthetic set of coins with the given amounts
+func newTestCoins(amounts ...uint64) []*Coin {
+	coins := []*Coin{}
+	for i, amount := range amounts {
+		coins = append(coins, &Coin{
+			OutputPointer: &transaction.TransactionOutputPointer{
+				TransactionHash: common.BytesToHash([]byte{byte(i)}),
+				OutputIndex:     uint16(i),
+			},
+			Amount: amount,
+		})
+	}
+
+	return coins
+}
+
+// Returns the amounts of a list of coins
+func coinAmounts(coins []*Coin) []uint64 {
+	amounts := []uint64{}
+	for _, coin := range coins {
+		amounts = append(amounts, coin.Amount)
+	}
+
+	return amounts
+}
+
+// Returns true if two lists of amounts are equal
+func amountsEqual(a []uint64, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Tests that largest first and smallest first select coins in the expected order
+func TestOrderedSelection(t *testing.T) {
+	coins := newTestCoins(5, 1, 8, 3, 2)
+	params := &CoinSelectionParams{Target: 9}
+
+	selected, ok := (&LargestFirst{}).SelectCoins(coins, params)
+	if !ok || !amountsEqual(coinAmounts(selected), []uint64{8, 5}) {
+		t.Fatalf(`Largest first selected %v`, coinAmounts(selected))
+	}
+
+	selected, ok = (&SmallestFirst{}).SelectCoins(coins, params)
+	if !ok || !amountsEqual(coinAmounts(selected), []uint64{1, 2, 3, 5}) {
+		t.Fatalf(`Smallest first selected %v`, coinAmounts(selected))
+	}
+
+	if _, ok := (&LargestFirst{}).SelectCoins(coins, &CoinSelectionParams{Target: 20}); ok {
+		t.Fatalf(`Selected coins for a target larger than the total`)
+	}
+}
+
+// Tests that the cost of spending an input is paid for by the selected coins
+func TestCostPerInput(t *testing.T) {
+	coins := newTestCoins(5, 1, 8, 3, 2)
+	params := &CoinSelectionParams{Target: 9, CostPerInput: 1}
+
+	// The coin worth 1 costs as much as it is worth, so it is never selected
+	selected, ok := (&SmallestFirst{}).SelectCoins(coins, params)
+	if !ok || !amountsEqual(coinAmounts(selected), []uint64{2, 3, 5, 8}) {
+		t.Fatalf(`Smallest first selected %v`, coinAmounts(selected))
+	}
+}
+
+// Tests that branch and bound finds a selection with no change
+func TestBranchAndBound(t *testing.T) {
+	coins := newTestCoins(10, 7, 6, 4, 3)
+	selector := &BranchAndBound{MaxTries: DefaultBranchAndBoundTries}
+
+	selected, ok := selector.SelectCoins(coins, &CoinSelectionParams{Target: 13})
+	if !ok || !amountsEqual(coinAmounts(selected), []uint64{10, 3}) {
+		t.Fatalf(`Branch and bound selected %v`, coinAmounts(selected))
+	}
+
+	// With a cost of change, a selection slightly over the target is accepted
+	selected, ok = selector.SelectCoins(newTestCoins(10, 7, 5), &CoinSelectionParams{Target: 9, CostOfChange: 1})
+	if !ok || !amountsEqual(coinAmounts(selected), []uint64{10}) {
+		t.Fatalf(`Branch and bound with cost of change selected %v`, coinAmounts(selected))
+	}
+
+	// The selection with the least excess is preferred
+	selected, ok = selector.SelectCoins(coins, &CoinSelectionParams{Target: 11, CostOfChange: 2})
+	if !ok || !amountsEqual(coinAmounts(selected), []uint64{7, 4}) {
+		t.Fatalf(`Branch and bound did not select the smallest excess, selected %v`, coinAmounts(selected))
+	}
+
+	// No exact solution exists, so branch and bound fails without a fallback
+	if _, ok := selector.SelectCoins(newTestCoins(10, 10), &CoinSelectionParams{Target: 15}); ok {
+		t.Fatalf(`Branch and bound found a changeless selection where none exists`)
+	}
+
+	selector.Fallback = &LargestFirst{}
+	selected, ok = selector.SelectCoins(newTestCoins(10, 10), &CoinSelectionParams{Target: 15})
+	if !ok || !amountsEqual(coinAmounts(selected), []uint64{10, 10}) {
+		t.Fatalf(`Branch and bound did not use its fallback, selected %v`, coinAmounts(selected))
+	}
+}
+
+// Tests that random improve covers the target without exceeding three times it once improving
+func TestRandomImprove(t *testing.T) {
+	coins := newTestCoins(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	params := &CoinSelectionParams{Target: 10}
+
+	for seed := int64(0); seed < 20; seed++ {
+		selector := &RandomImprove{Rand: rand.New(rand.NewSource(seed))}
+		selected, ok := selector.SelectCoins(coins, params)
+		if !ok {
+			t.Fatalf(`Random improve failed to select coins with seed %v`, seed)
+		}
+
+		var total uint64 = 0
+		for _, amount := range coinAmounts(selected) {
+			total += amount
+		}
+		if total < params.Target {
+			t.Fatalf(`Random improve selected %v which does not cover the target`, total)
+		}
+
+		// The same seed always produces the same selection
+		again, _ := (&RandomImprove{Rand: rand.New(rand.NewSource(seed))}).SelectCoins(coins, params)
+		if !amountsEqual(coinAmounts(selected), coinAmounts(again)) {
+			t.Fatalf(`Random improve is not deterministic for seed %v`, seed)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package wallet
+
+// Estimated sizes in bytes of the parts of a transaction, used to convert a fee rate into a fee
+const (
+	EstimatedBaseSize   = 21  // The version, input and output counts, and timestamp
+	EstimatedInputSize  = 193 // An output pointer, verification length, signature length, signature and encoded public key
+	EstimatedOutputSize = 40  // A receiver address and amount
+)
+
+// A fee rate estimator estimates the fee rate, in base units per byte, needed to confirm within a number of blocks
+type FeeRateEstimator interface {
+	EstimateFeeRate(targetBlocks int) (uint64, bool)
+}
+
+// Estimates the fee for a transaction with the given number of inputs and outputs to confirm within targetBlocks
+// Returns the fee and the fee rate it was computed from, and a bool indicating success
+func EstimateFee(estimator FeeRateEstimator, targetBlocks int, numInputs int, numOutputs int) (fee uint64, feeRate uint64, ok bool) {
+	feeRate, ok = estimator.EstimateFeeRate(targetBlocks)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return FeeForSize(feeRate, EstimatedSize(numInputs, numOutputs)), feeRate, true
+}
+
+// Returns the estimated size in bytes of a transaction with the given number of inputs and outputs
+func EstimatedSize(numInputs int, numOutputs int) int {
+	return EstimatedBaseSize + numInputs*EstimatedInputSize + numOutputs*EstimatedOutputSize
+}
+
+// Returns the fee paid by a transaction of the given size at a fee rate given in base units per byte
+func FeeForSize(feeRate uint64, size int) uint64 {
+	return feeRate * uint64(size)
+}
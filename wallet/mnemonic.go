@@ -0,0 +1,136 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	DefaultEntropyBits = 128  // The entropy of a newly generated mnemonic, which encodes to 12 words
+	MinEntropyBits     = 128  // The minimum entropy of a mnemonic
+	MaxEntropyBits     = 256  // The maximum entropy of a mnemonic
+	MnemonicIterations = 2048 // The number of PBKDF2 iterations used to stretch a mnemonic into a seed
+	MnemonicSeedLength = 64   // The number of bytes in a seed derived from a mnemonic
+
+	bitsPerWord = 11 // Each mnemonic word encodes 11 bits of the entropy and checksum
+)
+
+// Generates new random entropy with the given number of bits for creating a mnemonic
+// Returns a bool indicating success
+func NewEntropy(bitSize int) ([]byte, bool) {
+	if !isValidEntropyBits(bitSize) {
+		log.Warn("Entropy size must be a multiple of 32 bits between 128 and 256", "bits", bitSize)
+		return nil, false
+	}
+
+	entropy := make([]byte, bitSize/8)
+	if _, err := rand.Read(entropy); err != nil {
+		log.Error("Failed to generate entropy", "err", err)
+		return nil, false
+	}
+
+	return entropy, true
+}
+
+// Encodes entropy into a mnemonic phrase, appending a checksum of the entropy so that typos can be detected
+// Returns a bool indicating success
+func NewMnemonic(entropy []byte) (string, bool) {
+	entropyBits := len(entropy) * 8
+	if !isValidEntropyBits(entropyBits) {
+		log.Warn("Entropy size must be a multiple of 32 bits between 128 and 256", "bits", entropyBits)
+		return "", false
+	}
+
+	// The checksum is the first entropyBits/32 bits of the hash of the entropy
+	checksumBits := entropyBits / 32
+	checksum := sha256.Sum256(entropy)
+
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(checksum[0]>>(8-checksumBits))))
+
+	numWords := (entropyBits + checksumBits) / bitsPerWord
+	words := make([]string, numWords)
+	mask := big.NewInt(1<<bitsPerWord - 1)
+	for i := numWords - 1; i >= 0; i-- {
+		index := new(big.Int).And(data, mask)
+		words[i] = englishWordlist[index.Int64()]
+		data.Rsh(data, bitsPerWord)
+	}
+
+	return strings.Join(words, " "), true
+}
+
+// Decodes a mnemonic phrase back into its entropy
+// Returns a bool indicating success, which fails if a word is unknown or the checksum does not match
+func MnemonicToEntropy(mnemonic string) ([]byte, bool) {
+	words := strings.Fields(mnemonic)
+	totalBits := len(words) * bitsPerWord
+	checksumBits := totalBits / 33
+	entropyBits := totalBits - checksumBits
+	if len(words)%3 != 0 || !isValidEntropyBits(entropyBits) {
+		return nil, false
+	}
+
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := wordIndexes[word]
+		if !ok {
+			return nil, false
+		}
+		data.Lsh(data, bitsPerWord)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	data.Rsh(data, uint(checksumBits))
+	entropy := data.FillBytes(make([]byte, entropyBits/8))
+
+	expectedChecksum := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(expectedChecksum[0]>>(8-checksumBits)) {
+		return nil, false
+	}
+
+	return entropy, true
+}
+
+// Returns true if every word of the mnemonic is in the wordlist and its checksum matches
+func IsMnemonicValid(mnemonic string) bool {
+	_, ok := MnemonicToEntropy(mnemonic)
+	return ok
+}
+
+// Stretches a mnemonic and an optional passphrase into a wallet seed
+// The same mnemonic with a different passphrase yields an unrelated seed
+// Returns a bool indicating success, which fails if the mnemonic is invalid
+func MnemonicToSeed(mnemonic string, passphrase string) ([]byte, bool) {
+	if !IsMnemonicValid(mnemonic) {
+		return nil, false
+	}
+
+	normalizedMnemonic := strings.Join(strings.Fields(mnemonic), " ")
+	salt := []byte("mnemonic" + passphrase)
+	seed := pbkdf2.Key([]byte(normalizedMnemonic), salt, MnemonicIterations, MnemonicSeedLength, sha512.New)
+
+	return seed, true
+}
+
+// Maps each word of the wordlist to its index
+var wordIndexes = func() map[string]int {
+	indexes := make(map[string]int, len(englishWordlist))
+	for i, word := range englishWordlist {
+		indexes[word] = i
+	}
+
+	return indexes
+}()
+
+// Returns true if a number of entropy bits can be encoded as a mnemonic
+func isValidEntropyBits(bitSize int) bool {
+	return bitSize%32 == 0 && bitSize >= MinEntropyBits && bitSize <= MaxEntropyBits
+}
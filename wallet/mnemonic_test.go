@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/common"
+)
+
+// Test vectors from the BIP-39 specification, which use the passphrase TREZOR
+var mnemonicVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		entropy:  "00000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		entropy:  "80808080808080808080808080808080",
+		mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+}
+
+// Tests that entropy is encoded and decoded to the expected mnemonic and seed
+func TestMnemonicVectors(t *testing.T) {
+	for _, vector := range mnemonicVectors {
+		entropy, _ := hex.DecodeString(vector.entropy)
+
+		mnemonic, ok := NewMnemonic(entropy)
+		if !ok || mnemonic != vector.mnemonic {
+			t.Fatalf(`Mnemonic does not match. Expected: %v, Got: %v`, vector.mnemonic, mnemonic)
+		}
+
+		decodedEntropy, ok := MnemonicToEntropy(mnemonic)
+		if !ok || hex.EncodeToString(decodedEntropy) != vector.entropy {
+			t.Fatalf(`Entropy does not match. Expected: %v, Got: %x`, vector.entropy, decodedEntropy)
+		}
+
+		seed, ok := MnemonicToSeed(mnemonic, "TREZOR")
+		if !ok || hex.EncodeToString(seed) != vector.seed {
+			t.Fatalf(`Seed does not match. Expected: %v, Got: %x`, vector.seed, seed)
+		}
+	}
+}
+
+// Tests that mnemonics with unknown words or bad checksums are rejected
+func TestInvalidMnemonics(t *testing.T) {
+	invalidMnemonics := []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon testcoin",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"",
+	}
+
+	for _, mnemonic := range invalidMnemonics {
+		if IsMnemonicValid(mnemonic) {
+			t.Fatalf(`Invalid mnemonic was accepted: %v`, mnemonic)
+		}
+	}
+}
+
+// Tests that a passphrase changes the wallet derived from a mnemonic
+func TestMnemonicPassphrase(t *testing.T) {
+	walletA, _ := NewFromMnemonic(mnemonicVectors[1].mnemonic, "")
+	walletB, _ := NewFromMnemonic(mnemonicVectors[1].mnemonic, "hunter2")
+
+	addressA, _ := walletA.DeriveAddress(0)
+	addressB, _ := walletB.DeriveAddress(0)
+	if addressA.Equal(addressB) {
+		t.Fatalf(`Wallets with different passphrases derived the same address`)
+	}
+}
+
+// A set of used addresses standing in for the chain
+type testAddressHistory map[common.Address]bool
+
+func (h testAddressHistory) IsAddressUsed(address common.Address) bool {
+	return h[address]
+}
+
+// Tests that restoring a wallet finds every used address within the gap limit
+func TestRestoreWallet(t *testing.T) {
+	original, ok := New()
+	if !ok {
+		t.Fatalf(`Failed to create wallet`)
+	}
+
+	history := testAddressHistory{}
+	for _, index := range []uint32{0, 1, 4} {
+		address, _ := original.DeriveAddress(index)
+		history[address] = true
+	}
+
+	restored, ok := Restore(original.Mnemonic, "", 3, history)
+	if !ok {
+		t.Fatalf(`Failed to restore wallet`)
+	}
+	if len(restored.Accounts) != 5 {
+		t.Fatalf(`Expected 5 restored accounts, got %v`, len(restored.Accounts))
+	}
+
+	// An address beyond the gap limit is not found
+	restored, _ = Restore(original.Mnemonic, "", 2, history)
+	if len(restored.Accounts) != 2 {
+		t.Fatalf(`Expected 2 restored accounts with a smaller gap limit, got %v`, len(restored.Accounts))
+	}
+
+	next, _ := restored.NewAccount()
+	expected, _ := original.DeriveAddress(2)
+	if !next.Address.Equal(expected) {
+		t.Fatalf(`Restored wallet does not continue the sequence of addresses`)
+	}
+}
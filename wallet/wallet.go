@@ -0,0 +1,345 @@
+package wallet
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewCLu/TestcoinNode/account"
+	"github.com/AndrewCLu/TestcoinNode/common"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+	"github.com/AndrewCLu/TestcoinNode/logging"
+)
+
+const (
+	ExternalChainIndex  = 0  // The chain of addresses given out to receive payments
+	DefaultAccountIndex = 0  // The account all wallet addresses are derived under
+	DefaultGapLimit     = 20 // The number of consecutive unused addresses after which a restore stops scanning
+)
+
+var log = logging.Get("wallet")
+
+// Derives a child key, replaced in tests to simulate invalid children
+var deriveChild = (*crypto.ExtendedKey).Child
+
+// An address history reports whether an address has ever been used on the chain
+type AddressHistory interface {
+	IsAddressUsed(address common.Address) bool
+}
+
+// A wallet deterministically derives an unbounded sequence of accounts from a single seed
+// Accounts are derived at the path m/account'/0/index, so all of their addresses can also be
+// derived by a watch-only wallet holding only the extended public key of the account
+// Locking a wallet erases its secrets, after which it is watch-only until the seed is supplied to sign
+type Wallet struct {
+	Mnemonic   string              // The mnemonic phrase backing up the seed, empty if the wallet was not created from one or is locked
+	Seed       []byte              // The seed the wallet was created from, nil for watch-only and locked wallets
+	AccountKey *crypto.ExtendedKey // The extended key at m/account', which is public for watch-only and locked wallets
+	Accounts   []*account.Account  // Accounts derived so far, in order of their child number
+	NextIndex  uint32              // The child number the next account is derived from
+
+	indexes map[common.Address]uint32 // The child number each account was derived from
+}
+
+// Creates a wallet from a newly generated mnemonic phrase with no passphrase
+// The mnemonic should be written down, since it is the only way to restore the wallet
+// Returns a bool indicating success
+func New() (*Wallet, bool) {
+	entropy, ok := NewEntropy(DefaultEntropyBits)
+	if !ok {
+		return nil, false
+	}
+
+	mnemonic, ok := NewMnemonic(entropy)
+	if !ok {
+		return nil, false
+	}
+
+	return NewFromMnemonic(mnemonic, "")
+}
+
+// Creates a wallet from a mnemonic phrase and optional passphrase
+// Returns a bool indicating success
+func NewFromMnemonic(mnemonic string, passphrase string) (*Wallet, bool) {
+	seed, ok := MnemonicToSeed(mnemonic, passphrase)
+	if !ok {
+		log.Warn("Invalid mnemonic phrase")
+		return nil, false
+	}
+
+	wallet, ok := NewFromSeed(seed)
+	if !ok {
+		return nil, false
+	}
+	wallet.Mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+
+	return wallet, true
+}
+
+// Restores a wallet from a mnemonic phrase, scanning the chain for all addresses the wallet has used
+// Accounts are derived in sequence until gapLimit consecutive addresses have never been used,
+// and every account up to the last used address is added to the wallet
+// Returns a bool indicating success
+func Restore(mnemonic string, passphrase string, gapLimit int, history AddressHistory) (*Wallet, bool) {
+	if gapLimit <= 0 {
+		log.Warn("Gap limit must be positive", "gapLimit", gapLimit)
+		return nil, false
+	}
+
+	wallet, ok := NewFromMnemonic(mnemonic, passphrase)
+	if !ok {
+		return nil, false
+	}
+
+	derived := []*account.Account{}
+	lastUsed := -1
+	nextIndex := uint32(0)
+	for len(derived)-1-lastUsed < gapLimit {
+		act, childIndex, ok := wallet.deriveAccountFrom(nextIndex)
+		if !ok {
+			return nil, false
+		}
+		derived = append(derived, act)
+		wallet.indexes[act.Address] = childIndex
+
+		if history.IsAddressUsed(act.Address) {
+			lastUsed = len(derived) - 1
+			wallet.NextIndex = childIndex + 1
+		}
+		nextIndex = childIndex + 1
+	}
+
+	// Accounts past the last used address are derived again when they are needed
+	for _, act := range derived[lastUsed+1:] {
+		act.Lock()
+		delete(wallet.indexes, act.Address)
+	}
+	wallet.Accounts = derived[:lastUsed+1]
+
+	return wallet, true
+}
+
+// Creates a wallet from an existing seed, which the wallet keeps its own copy of
+// Returns a bool indicating success
+func NewFromSeed(seed []byte) (*Wallet, bool) {
+	masterKey, err := crypto.NewMasterKey(seed)
+	if err != nil {
+		log.Warn("Failed to create master key from seed", "err", err)
+		return nil, false
+	}
+	defer masterKey.Zero()
+
+	accountKey, err := masterKey.Child(crypto.HardenedKeyStart + DefaultAccountIndex)
+	if err != nil {
+		log.Error("Failed to derive account key", "err", err)
+		return nil, false
+	}
+
+	wallet := Wallet{
+		Seed:       append([]byte{}, seed...),
+		AccountKey: accountKey,
+		Accounts:   []*account.Account{},
+		indexes:    make(map[common.Address]uint32),
+	}
+
+	return &wallet, true
+}
+
+// Creates a watch-only wallet from a serialized extended public key
+// A watch-only wallet can derive account addresses and public keys, but cannot sign
+// Returns a bool indicating success
+func NewWatchOnly(extendedPublicKey string) (*Wallet, bool) {
+	accountKey, err := crypto.ParseExtendedKey(extendedPublicKey)
+	if err != nil {
+		log.Warn("Invalid extended public key", "err", err)
+		return nil, false
+	}
+
+	wallet := Wallet{
+		AccountKey: accountKey.Neuter(),
+		Accounts:   []*account.Account{},
+		indexes:    make(map[common.Address]uint32),
+	}
+
+	return &wallet, true
+}
+
+// Returns true if the wallet can only derive public keys
+func (w *Wallet) IsWatchOnly() bool {
+	return !w.AccountKey.IsPrivate
+}
+
+// Returns the serialized extended public key of the wallet, which can be used to create a watch-only wallet
+func (w *Wallet) ExtendedPublicKey() string {
+	return w.AccountKey.Neuter().String()
+}
+
+// Derives the next account in the sequence and adds it to the wallet
+// Returns a bool indicating success
+func (w *Wallet) NewAccount() (*account.Account, bool) {
+	act, childIndex, ok := w.deriveAccountFrom(w.NextIndex)
+	if !ok {
+		return nil, false
+	}
+
+	w.Accounts = append(w.Accounts, act)
+	w.indexes[act.Address] = childIndex
+	w.NextIndex = childIndex + 1
+
+	return act, true
+}
+
+// Derives the account at the given index without adding it to the wallet
+// If the key at the index is invalid, the account at the next valid index is derived instead
+// Accounts of watch-only wallets have no private key
+// Returns a bool indicating success
+func (w *Wallet) DeriveAccount(index uint32) (*account.Account, bool) {
+	act, _, ok := w.deriveAccountFrom(index)
+
+	return act, ok
+}
+
+// Derives the account at the first child number from index onwards whose key is valid
+// BIP32 requires skipping child numbers that derive an invalid key, which happens with negligible probability
+// Returns the account, the child number it was derived from, and a bool indicating success
+func (w *Wallet) deriveAccountFrom(index uint32) (*account.Account, uint32, bool) {
+	chainKey, err := deriveChild(w.AccountKey, ExternalChainIndex)
+	if err != nil {
+		log.Error("Failed to derive external chain key", "err", err)
+		return nil, 0, false
+	}
+	defer chainKey.Zero()
+
+	for ; index < crypto.HardenedKeyStart; index++ {
+		key, err := deriveChild(chainKey, index)
+		if errors.Is(err, crypto.ErrInvalidChild) {
+			continue
+		}
+		if err != nil {
+			log.Error("Failed to derive account", "index", index, "err", err)
+			return nil, 0, false
+		}
+
+		encodedPublicKey, encodedPrivateKey, err := key.EncodedKeys()
+		key.Zero()
+		if err != nil {
+			log.Error("Failed to encode account keys", "index", index, "err", err)
+			return nil, 0, false
+		}
+
+		return account.NewFromKeys(encodedPublicKey, encodedPrivateKey), index, true
+	}
+
+	return nil, 0, false
+}
+
+// Erases the wallet's seed, mnemonic and private keys from memory, including those of its accounts
+// The wallet can still derive addresses, and signs for its accounts once the seed is supplied to SignWith
+func (w *Wallet) Lock() {
+	for i := range w.Seed {
+		w.Seed[i] = 0
+	}
+	w.Seed = nil
+	w.Mnemonic = ""
+
+	if w.AccountKey.IsPrivate {
+		publicKey := w.AccountKey.Neuter()
+		w.AccountKey.Zero()
+		w.AccountKey = publicKey
+	}
+
+	for _, act := range w.Accounts {
+		act.Lock()
+	}
+}
+
+// Derives the private key of one of the wallet's accounts from its seed, calls sign with the unlocked account,
+// and locks the account again before returning
+// Returns the result of sign, or false if the seed does not belong to the wallet or the address is not one of its accounts
+func (w *Wallet) SignWith(seed []byte, address common.Address, sign func(act *account.Account) bool) bool {
+	index, ok := w.indexes[address]
+	if !ok {
+		log.Warn("Address is not an account of this wallet", "address", address.Hex())
+		return false
+	}
+
+	unlocked, ok := NewFromSeed(seed)
+	if !ok {
+		return false
+	}
+	defer unlocked.Lock()
+
+	if unlocked.ExtendedPublicKey() != w.ExtendedPublicKey() {
+		log.Warn("Seed does not belong to this wallet")
+		return false
+	}
+
+	act, childIndex, ok := unlocked.deriveAccountFrom(index)
+	if !ok || childIndex != index {
+		return false
+	}
+	defer act.Lock()
+
+	return sign(act)
+}
+
+// Derives the address of the account at the given index
+// Returns a bool indicating success
+func (w *Wallet) DeriveAddress(index uint32) (common.Address, bool) {
+	act, ok := w.DeriveAccount(index)
+	if !ok {
+		return common.Address{}, false
+	}
+
+	return act.Address, true
+}
+
+// Derives the extended key at a path such as m/0'/0/1 from a seed
+// Returns a bool indicating success
+func DeriveKeyFromPath(seed []byte, path string) (*crypto.ExtendedKey, bool) {
+	indexes, ok := ParseDerivationPath(path)
+	if !ok {
+		return nil, false
+	}
+
+	masterKey, err := crypto.NewMasterKey(seed)
+	if err != nil {
+		log.Warn("Failed to create master key from seed", "err", err)
+		return nil, false
+	}
+
+	key, err := masterKey.DerivePath(indexes)
+	if err != nil {
+		log.Warn("Failed to derive key path", "path", path, "err", err)
+		return nil, false
+	}
+
+	return key, true
+}
+
+// Parses a derivation path such as m/0'/0/1 into child indexes, where ' marks a hardened index
+// Returns a bool indicating success
+func ParseDerivationPath(path string) ([]uint32, bool) {
+	components := strings.Split(path, "/")
+	if len(components) == 0 || components[0] != "m" {
+		return nil, false
+	}
+
+	indexes := []uint32{}
+	for _, component := range components[1:] {
+		hardened := strings.HasSuffix(component, "'")
+		component = strings.TrimSuffix(component, "'")
+
+		index, err := strconv.ParseUint(component, 10, 32)
+		if err != nil || index >= crypto.HardenedKeyStart {
+			return nil, false
+		}
+
+		if hardened {
+			index += crypto.HardenedKeyStart
+		}
+		indexes = append(indexes, uint32(index))
+	}
+
+	return indexes, true
+}
@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/AndrewCLu/TestcoinNode/account"
+	"github.com/AndrewCLu/TestcoinNode/crypto"
+)
+
+var testSeed = []byte("testcoin wallet seed for testing")
+
+// Tests that two wallets created from the same seed derive the same sequence of accounts
+func TestWalletFromSeed(t *testing.T) {
+	walletA, ok := NewFromSeed(testSeed)
+	if !ok {
+		t.Fatalf(`Failed to create wallet from seed`)
+	}
+	walletB, _ := NewFromSeed(testSeed)
+
+	for i := 0; i < 5; i++ {
+		accountA, ok := walletA.NewAccount()
+		if !ok {
+			t.Fatalf(`Failed to derive account %v`, i)
+		}
+		accountB, _ := walletB.NewAccount()
+
+		if !accountA.Address.Equal(accountB.Address) {
+			t.Fatalf(`Accounts at index %v do not match. A: %v, B: %v`, i, accountA.Address.Hex(), accountB.Address.Hex())
+		}
+	}
+
+	if walletA.Accounts[0].Address.Equal(walletA.Accounts[1].Address) {
+		t.Fatalf(`Wallet derived the same address twice`)
+	}
+}
+
+// Tests that a watch-only wallet derives the same addresses as the wallet it was exported from
+func TestWatchOnlyWallet(t *testing.T) {
+	wallet, _ := NewFromSeed(testSeed)
+	watchOnly, ok := NewWatchOnly(wallet.ExtendedPublicKey())
+	if !ok {
+		t.Fatalf(`Failed to create watch-only wallet`)
+	}
+
+	if !watchOnly.IsWatchOnly() || wallet.IsWatchOnly() {
+		t.Fatalf(`Wallets do not report watch-only status correctly`)
+	}
+
+	for i := uint32(0); i < 5; i++ {
+		act, _ := wallet.DeriveAccount(i)
+		watchOnlyAct, ok := watchOnly.DeriveAccount(i)
+		if !ok {
+			t.Fatalf(`Watch-only wallet failed to derive account %v`, i)
+		}
+
+		if !act.Address.Equal(watchOnlyAct.Address) {
+			t.Fatalf(`Watch-only address at index %v does not match`, i)
+		}
+
+		if watchOnlyAct.PrivateKey != nil {
+			t.Fatalf(`Watch-only account has a private key`)
+		}
+	}
+}
+
+// Tests that derivation paths are parsed into the correct indexes
+func TestParseDerivationPath(t *testing.T) {
+	indexes, ok := ParseDerivationPath("m/0'/0/7")
+	if !ok {
+		t.Fatalf(`Failed to parse valid derivation path`)
+	}
+
+	expected := []uint32{crypto.HardenedKeyStart, 0, 7}
+	for i := range expected {
+		if indexes[i] != expected[i] {
+			t.Fatalf(`Parsed path does not match. Expected: %v, Parsed: %v`, expected, indexes)
+		}
+	}
+
+	wallet, _ := NewFromSeed(testSeed)
+	key, _ := DeriveKeyFromPath(testSeed, "m/0'/0/7")
+	encodedPublicKey, _, _ := key.EncodedKeys()
+	act, _ := wallet.DeriveAccount(7)
+	if string(encodedPublicKey) != string(act.PublicKey) {
+		t.Fatalf(`Key derived from path does not match wallet account`)
+	}
+
+	for _, path := range []string{"0'/1", "m/x", "m/2147483648"} {
+		if _, ok := ParseDerivationPath(path); ok {
+			t.Fatalf(`Parsed invalid derivation path %v`, path)
+		}
+	}
+}
+
+// Tests that child numbers deriving an invalid key are skipped rather than failing
+func TestWalletSkipsInvalidChild(t *testing.T) {
+	wallet, _ := NewFromSeed(testSeed)
+	expected, _ := wallet.DeriveAddress(2)
+
+	// Pretend the key at child number 1 of the external chain is invalid
+	deriveChild = func(key *crypto.ExtendedKey, index uint32) (*crypto.ExtendedKey, error) {
+		if key.Depth == 2 && index == 1 {
+			return nil, crypto.ErrInvalidChild
+		}
+		return key.Child(index)
+	}
+	defer func() { deriveChild = (*crypto.ExtendedKey).Child }()
+
+	wallet.NewAccount()
+	act, ok := wallet.NewAccount()
+	if !ok {
+		t.Fatalf(`Failed to derive an account after an invalid child`)
+	}
+	if !act.Address.Equal(expected) || wallet.NextIndex != 3 {
+		t.Fatalf(`Expected the second account to be derived from child 2, next index is %v`, wallet.NextIndex)
+	}
+}
+
+// Tests that a locked wallet holds no secrets but can still sign for its accounts when given the seed
+func TestLockWallet(t *testing.T) {
+	wallet, _ := NewFromSeed(testSeed)
+	act, _ := wallet.NewAccount()
+	publicKey := wallet.ExtendedPublicKey()
+
+	wallet.Lock()
+	if wallet.Seed != nil || wallet.Mnemonic != "" || !wallet.IsWatchOnly() || !act.IsLocked() {
+		t.Fatalf(`Locked wallet still holds secrets`)
+	}
+	if wallet.ExtendedPublicKey() != publicKey {
+		t.Fatalf(`Locking changed the wallet's extended public key`)
+	}
+
+	// Accounts derived after locking have no private key
+	next, ok := wallet.NewAccount()
+	if !ok || !next.IsLocked() {
+		t.Fatalf(`Failed to derive a locked account from a locked wallet`)
+	}
+
+	other, _ := NewFromSeed([]byte("another testcoin wallet seed...."))
+	if wallet.SignWith(other.Seed, act.Address, func(*account.Account) bool { return true }) {
+		t.Fatalf(`Signed with a seed that does not belong to the wallet`)
+	}
+
+	var signingAccount *account.Account
+	signed := wallet.SignWith(testSeed, next.Address, func(unlocked *account.Account) bool {
+		signingAccount = unlocked
+		return !unlocked.IsLocked() && unlocked.Address.Equal(next.Address)
+	})
+	if !signed || !signingAccount.IsLocked() {
+		t.Fatalf(`Failed to sign with an account unlocked only while signing`)
+	}
+	if testSeed[0] == 0 {
+		t.Fatalf(`Signing erased the caller's seed`)
+	}
+}